@@ -0,0 +1,71 @@
+package candlestick
+
+// bitWriter packs bits most significant bit first. Bits are collected in
+// acc and moved to buf a byte at a time; flush writes out the remainder.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits int
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// writeBits writes the n lowest bits of v.
+func (w *bitWriter) writeBits(v uint64, n int) {
+	if n > 32 {
+		w.writeBits(v>>32, n-32)
+		n = 32
+	}
+	w.acc = w.acc<<uint(n) | v&(1<<uint(n)-1)
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.nbits -= 8
+		w.buf = append(w.buf, byte(w.acc>>uint(w.nbits)))
+	}
+}
+
+func (w *bitWriter) flush() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc<<uint(8-w.nbits)))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
+
+// bitReader reads what bitWriter wrote. Reading past the end of the data
+// yields zero bits and sets err, so callers only need to check err once.
+type bitReader struct {
+	data  []byte
+	pos   int
+	acc   uint64
+	nbits int
+	err   error
+}
+
+func (r *bitReader) readBit() bool {
+	return r.readBits(1) == 1
+}
+
+func (r *bitReader) readBits(n int) uint64 {
+	if n > 32 {
+		hi := r.readBits(n - 32)
+		return hi<<32 | r.readBits(32)
+	}
+	for r.nbits < n {
+		if r.pos >= len(r.data) {
+			r.err = ErrTruncated
+			return 0
+		}
+		r.acc = r.acc<<8 | uint64(r.data[r.pos])
+		r.pos++
+		r.nbits += 8
+	}
+	r.nbits -= n
+	return r.acc >> uint(r.nbits) & (1<<uint(n) - 1)
+}
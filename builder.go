@@ -0,0 +1,176 @@
+package candlestick
+
+import "errors"
+
+var ErrLateTrade = errors.New("candlestick: trade belongs to a closed candle")
+
+type Trade struct {
+	Price    float64 `json:"p"`
+	Quantity float64 `json:"q"`
+	// TakerBuy is set when the taker of the trade was the buyer, which
+	// counts the quantity towards the candle's TakerVolume.
+	TakerBuy bool  `json:"tb"`
+	Time     int64 `json:"t"`
+}
+
+// CandleBuilder aggregates the trades of a symbol into candles of a fixed
+// interval. Trades must arrive in time order. A candle is closed once a
+// trade of a later candle arrives, or once Advance moves past its end;
+// intervals without trades are closed as missing candles.
+//
+// Closed candles are placed into CandleSet blocks. The current block holds
+// the candles closed so far, starting at index 0 with candles before the
+// first trade marked missing. Once a block is full it is marked complete
+// and can be collected with CompletedBlocks.
+type CandleBuilder struct {
+	symbol    string
+	interval  int64
+	started   bool
+	next      int64
+	open      Candle
+	hasOpen   bool
+	block     *CandleSet
+	completed []*CandleSet
+}
+
+func NewCandleBuilder(symbol string, interval int64) *CandleBuilder {
+	return &CandleBuilder{
+		symbol:   symbol,
+		interval: interval,
+	}
+}
+
+// Add consumes a trade and returns the candles it closed.
+func (b *CandleBuilder) Add(t Trade) ([]Candle, error) {
+	start := alignDown(t.Time, b.interval)
+	if b.started && start < b.next {
+		return nil, ErrLateTrade
+	}
+	closed := b.closeUntil(start)
+
+	// update open candle
+	if !b.hasOpen {
+		b.open = Candle{
+			Open: t.Price,
+			High: t.Price,
+			Low:  t.Price,
+			Time: start,
+		}
+		b.hasOpen = true
+	}
+	if t.Price > b.open.High {
+		b.open.High = t.Price
+	}
+	if t.Price < b.open.Low {
+		b.open.Low = t.Price
+	}
+	b.open.Close = t.Price
+	b.open.Volume += t.Quantity
+	if t.TakerBuy {
+		b.open.TakerVolume += t.Quantity
+	}
+	b.open.NumberOfTrades++
+
+	if t.Time > b.block.Meta.LastUpdate {
+		b.block.Meta.LastUpdate = t.Time
+	}
+	return closed, nil
+}
+
+// Advance closes all candles that end at or before now and returns them.
+func (b *CandleBuilder) Advance(now int64) []Candle {
+	if !b.started {
+		return nil
+	}
+	closed := b.closeUntil(alignDown(now, b.interval))
+	if now > b.block.Meta.LastUpdate {
+		b.block.Meta.LastUpdate = now
+	}
+	return closed
+}
+
+// Current returns the candle still open, if any trade has been added to it.
+func (b *CandleBuilder) Current() (Candle, bool) {
+	return b.open, b.hasOpen
+}
+
+// Block returns a copy of the block the next closed candle is placed in.
+func (b *CandleBuilder) Block() *CandleSet {
+	if b.block == nil {
+		return nil
+	}
+	candles := make([]Candle, len(b.block.Candles))
+	copy(candles, b.block.Candles)
+	return &CandleSet{
+		Candles: candles,
+		Meta:    b.block.Meta,
+	}
+}
+
+// CompletedBlocks returns the blocks completed since the previous call.
+func (b *CandleBuilder) CompletedBlocks() []*CandleSet {
+	completed := b.completed
+	b.completed = nil
+	return completed
+}
+
+// closeUntil closes all candles starting before start.
+func (b *CandleBuilder) closeUntil(start int64) []Candle {
+	if !b.started {
+		b.started = true
+		b.next = start
+		b.block = b.newBlock(UnixToBlock(start, b.interval))
+		return nil
+	}
+	var closed []Candle
+	for b.next < start {
+		c := Candle{Time: b.next, Missing: true}
+		if b.hasOpen && b.open.Time == b.next {
+			c = b.open
+			b.hasOpen = false
+		}
+		b.place(c)
+		closed = append(closed, c)
+		b.next += b.interval
+	}
+	return closed
+}
+
+func (b *CandleBuilder) place(c Candle) {
+
+	// fill candles before the first one
+	index := b.block.Index(c.Time)
+	for i := int64(len(b.block.Candles)); i < index; i++ {
+		b.block.Candles = append(b.block.Candles, Candle{Time: b.block.TimeStampAtIndex(i), Missing: true})
+	}
+	b.block.Candles = append(b.block.Candles, c)
+
+	// rotate full blocks
+	if int64(len(b.block.Candles)) == CandleSetSize {
+		b.block.Meta.Complete = true
+		b.completed = append(b.completed, b.block)
+		next := b.newBlock(b.block.Meta.Block + 1)
+		next.Meta.LastUpdate = b.block.Meta.LastUpdate
+		b.block = next
+	}
+}
+
+func (b *CandleBuilder) newBlock(block int64) *CandleSet {
+	return &CandleSet{
+		Candles: make([]Candle, 0, CandleSetSize),
+		Meta: DataSetMeta{
+			Block:    block,
+			Symbol:   b.symbol,
+			Interval: b.interval,
+		},
+	}
+}
+
+// alignDown rounds unixTime down to a multiple of interval.
+func alignDown(unixTime int64, interval int64) int64 {
+	r := unixTime % interval
+	if r < 0 {
+		r += interval
+	}
+	return unixTime - r
+}
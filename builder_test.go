@@ -0,0 +1,72 @@
+package candlestick
+
+import (
+	"fmt"
+	"log"
+	"testing"
+)
+
+func TestCandleBuilder(t *testing.T) {
+	b := NewCandleBuilder("AAPLUSD", Interval1m)
+	base := BlockToUnix(10, Interval1m) + 2*Interval1m
+
+	trades := []Trade{
+		{Price: 10, Quantity: 1, TakerBuy: true, Time: base + 1},
+		{Price: 12, Quantity: 2, Time: base + 20},
+		{Price: 9, Quantity: 1, TakerBuy: true, Time: base + 59},
+		{Price: 11, Quantity: 3, Time: base + 3*Interval1m + 5},
+	}
+	var closed []Candle
+	for _, trade := range trades {
+		c, err := b.Add(trade)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		closed = append(closed, c...)
+	}
+	expected := Candle{Open: 10, High: 12, Low: 9, Close: 9, Volume: 4, TakerVolume: 2, NumberOfTrades: 3, Time: base}
+	if len(closed) != 3 || closed[0] != expected || !closed[1].Missing || !closed[2].Missing || closed[2].Time != base+2*Interval1m {
+		fmt.Printf("unexpected closed candles: %v\n", closed)
+		t.FailNow()
+	}
+	if c, ok := b.Current(); !ok || c.Open != 11 || c.Time != base+3*Interval1m {
+		fmt.Printf("unexpected open candle: %v\n", c)
+		t.FailNow()
+	}
+	if _, err := b.Add(Trade{Price: 1, Time: base}); err != ErrLateTrade {
+		fmt.Printf("expected late trade, got %v\n", err)
+		t.FailNow()
+	}
+
+	block := b.Block()
+	if block.Meta.Block != 10 || block.Meta.Complete || len(block.Candles) != 5 || !block.Candles[0].Missing || block.Candles[2] != expected {
+		fmt.Printf("unexpected block: %v\n", block.Meta)
+		t.FailNow()
+	}
+	if block.Meta.LastUpdate != trades[3].Time {
+		fmt.Printf("unexpected last update %d\n", block.Meta.LastUpdate)
+		t.FailNow()
+	}
+
+	// advancing past the end of the block completes it
+	closed = b.Advance(BlockToUnix(11, Interval1m) + 30)
+	if int64(len(closed)) != CandleSetSize-5 || closed[0].Open != 11 {
+		fmt.Printf("unexpected number of closed candles %d\n", len(closed))
+		t.FailNow()
+	}
+	completed := b.CompletedBlocks()
+	if len(completed) != 1 || !completed[0].Meta.Complete || int64(len(completed[0].Candles)) != CandleSetSize {
+		fmt.Printf("expected a completed block\n")
+		t.FailNow()
+	}
+	for i, c := range completed[0].Candles {
+		if c.Time != completed[0].TimeStampAtIndex(int64(i)) {
+			fmt.Printf("candle %d has time %d\n", i, c.Time)
+			t.FailNow()
+		}
+	}
+	if b.Block().Meta.Block != 11 || len(b.CompletedBlocks()) != 0 {
+		fmt.Printf("expected next block to be open\n")
+		t.FailNow()
+	}
+}
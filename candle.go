@@ -85,6 +85,10 @@ func (b *CandleSet) UnixLast() int64 {
 }
 
 func EncodeCandleSet(b *CandleSet) ([]byte, error) {
+	return EncodeCandleSetWithCodec(b, CodecRaw)
+}
+
+func EncodeCandleSetWithCodec(b *CandleSet, codec Codec) ([]byte, error) {
 
 	// encode meta data
 	var metaBuf bytes.Buffer
@@ -95,59 +99,101 @@ func EncodeCandleSet(b *CandleSet) ([]byte, error) {
 	metaBytes := metaBuf.Bytes()
 
 	// create main buffer
-	cSize := candleSetByteSize
-	candleDataSize := 8 + cSize*len(b.Candles)
-	buf := make([]byte, candleDataSize+len(metaBytes))
+	candleDataSize := 8 + candleSetByteSize*len(b.Candles)
+	buf := make([]byte, headerByteSize, headerByteSize+candleDataSize+len(metaBytes)+checksumByteSize)
 
-	// add number of candles
-	binary.BigEndian.PutUint64(buf[0:], uint64(len(b.Candles)))
+	// add header
+	putBlockHeader(buf, candleSetMagic, blockHeader{
+		version: formatVersion1,
+		codec:   codec,
+		flags:   flagChecksum,
+		fields:  uint16(CandleFieldsAll),
+	})
 
 	// add candle data
-	for i, c := range b.Candles {
-		binary.BigEndian.PutUint64(buf[8+i*cSize+0:], math.Float64bits(c.Open))
-		binary.BigEndian.PutUint64(buf[8+i*cSize+8:], math.Float64bits(c.High))
-		binary.BigEndian.PutUint64(buf[8+i*cSize+16:], math.Float64bits(c.Low))
-		binary.BigEndian.PutUint64(buf[8+i*cSize+24:], math.Float64bits(c.Close))
-		binary.BigEndian.PutUint64(buf[8+i*cSize+32:], math.Float64bits(c.Volume))
-		binary.BigEndian.PutUint64(buf[8+i*cSize+40:], math.Float64bits(c.TakerVolume))
-		binary.BigEndian.PutUint64(buf[8+i*cSize+48:], uint64(c.NumberOfTrades))
-		binary.BigEndian.PutUint64(buf[8+i*cSize+56:], uint64(c.Time))
-		isMissing := uint8(0)
-		if c.Missing {
-			isMissing = 1
-		}
-		buf[8+i*cSize+64] = isMissing
+	switch codec {
+	case CodecRaw:
+		buf = appendCandlesRaw(buf, b.Candles)
+	case CodecGorilla:
+		buf = appendCandlesGorilla(buf, b.Candles)
+	case CodecColumnar:
+		buf = appendCandleColumns(buf, NewCandleColumns(b))
+	default:
+		return nil, ErrUnsupportedCodec
 	}
 
 	// copy meta bytes
-	copy(buf[8+len(b.Candles)*cSize:], metaBytes)
+	buf = append(buf, metaBytes...)
+
+	// add checksum
+	buf = append(buf, make([]byte, checksumByteSize)...)
+	putChecksum(buf)
 
 	return buf, nil
 }
 
+func appendCandlesRaw(buf []byte, candles []Candle) []byte {
+	cSize := candleSetByteSize
+	o := len(buf)
+	buf = append(buf, make([]byte, 8+cSize*len(candles))...)
+
+	// add number of candles
+	binary.BigEndian.PutUint64(buf[o:], uint64(len(candles)))
+
+	// add candle data
+	for i := range candles {
+		putCandle(buf[o+8+i*cSize:], &candles[i], CandleFieldsAll)
+	}
+
+	return buf
+}
+
 func DecodeCandleSet(data []byte) (*CandleSet, error) {
+	h, ok := readBlockHeader(data, candleSetMagic)
+	if !ok {
+		return decodeCandleSetRaw(data, CandleFieldsAll)
+	}
+	if h.version != formatVersion1 {
+		return nil, ErrUnsupportedVersion
+	}
+	payload, err := blockPayload(data, h)
+	if err != nil {
+		return nil, err
+	}
+	switch h.codec {
+	case CodecRaw:
+		return decodeCandleSetRaw(payload, CandleField(h.fields))
+	case CodecGorilla:
+		return decodeCandleSetGorilla(payload)
+	case CodecColumnar:
+		c, err := decodeCandleColumns(payload, CandleField(h.fields), CandleFieldsAll)
+		if err != nil {
+			return nil, err
+		}
+		return c.CandleSet(), nil
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+}
 
-	cSize := candleSetByteSize
-	numberOfCandles := int(binary.BigEndian.Uint64(data[0:]))
+func decodeCandleSetRaw(data []byte, fields CandleField) (*CandleSet, error) {
+
+	if fields == 0 || fields&^CandleFieldsAll != 0 {
+		return nil, ErrCorruptHeader
+	}
+	cSize := fields.recordSize()
+	numberOfCandles, err := readCount(data, cSize)
+	if err != nil {
+		return nil, err
+	}
 
 	candles := make([]Candle, numberOfCandles)
 	for i := 0; i < numberOfCandles; i++ {
-		candles[i] = Candle{
-			Open:           math.Float64frombits(binary.BigEndian.Uint64(data[8+i*cSize+0:])),
-			High:           math.Float64frombits(binary.BigEndian.Uint64(data[8+i*cSize+8:])),
-			Low:            math.Float64frombits(binary.BigEndian.Uint64(data[8+i*cSize+16:])),
-			Close:          math.Float64frombits(binary.BigEndian.Uint64(data[8+i*cSize+24:])),
-			Volume:         math.Float64frombits(binary.BigEndian.Uint64(data[8+i*cSize+32:])),
-			TakerVolume:    math.Float64frombits(binary.BigEndian.Uint64(data[8+i*cSize+40:])),
-			NumberOfTrades: int64(binary.BigEndian.Uint64(data[8+i*cSize+48:])),
-			Time:           int64(binary.BigEndian.Uint64(data[8+i*cSize+56:])),
-			Missing:        data[8+i*cSize+64] == 1,
-		}
+		candles[i] = readCandle(data[8+i*cSize:], fields)
 	}
 
-	metaBytes := bytes.NewReader(data[8+numberOfCandles*cSize:])
 	var meta DataSetMeta
-	err := gob.NewDecoder(metaBytes).Decode(&meta)
+	err = decodeMeta(data[8+numberOfCandles*cSize:], &meta)
 	if err != nil {
 		return nil, err
 	}
@@ -160,6 +206,75 @@ func DecodeCandleSet(data []byte) (*CandleSet, error) {
 	return cs, nil
 }
 
+// putCandle writes the selected fields of c in record order.
+func putCandle(buf []byte, c *Candle, fields CandleField) {
+	o := 0
+	for _, field := range candleFieldOrder {
+		if fields&field == 0 {
+			continue
+		}
+		switch field {
+		case FieldOpen:
+			binary.BigEndian.PutUint64(buf[o:], math.Float64bits(c.Open))
+		case FieldHigh:
+			binary.BigEndian.PutUint64(buf[o:], math.Float64bits(c.High))
+		case FieldLow:
+			binary.BigEndian.PutUint64(buf[o:], math.Float64bits(c.Low))
+		case FieldClose:
+			binary.BigEndian.PutUint64(buf[o:], math.Float64bits(c.Close))
+		case FieldVolume:
+			binary.BigEndian.PutUint64(buf[o:], math.Float64bits(c.Volume))
+		case FieldTakerVolume:
+			binary.BigEndian.PutUint64(buf[o:], math.Float64bits(c.TakerVolume))
+		case FieldNumberOfTrades:
+			binary.BigEndian.PutUint64(buf[o:], uint64(c.NumberOfTrades))
+		case FieldTime:
+			binary.BigEndian.PutUint64(buf[o:], uint64(c.Time))
+		case FieldMissing:
+			isMissing := uint8(0)
+			if c.Missing {
+				isMissing = 1
+			}
+			buf[o] = isMissing
+		}
+		o += field.byteSize()
+	}
+}
+
+// readCandle reads a record holding the selected fields, leaving absent
+// fields at their zero value.
+func readCandle(data []byte, fields CandleField) Candle {
+	var c Candle
+	o := 0
+	for _, field := range candleFieldOrder {
+		if fields&field == 0 {
+			continue
+		}
+		switch field {
+		case FieldOpen:
+			c.Open = math.Float64frombits(binary.BigEndian.Uint64(data[o:]))
+		case FieldHigh:
+			c.High = math.Float64frombits(binary.BigEndian.Uint64(data[o:]))
+		case FieldLow:
+			c.Low = math.Float64frombits(binary.BigEndian.Uint64(data[o:]))
+		case FieldClose:
+			c.Close = math.Float64frombits(binary.BigEndian.Uint64(data[o:]))
+		case FieldVolume:
+			c.Volume = math.Float64frombits(binary.BigEndian.Uint64(data[o:]))
+		case FieldTakerVolume:
+			c.TakerVolume = math.Float64frombits(binary.BigEndian.Uint64(data[o:]))
+		case FieldNumberOfTrades:
+			c.NumberOfTrades = int64(binary.BigEndian.Uint64(data[o:]))
+		case FieldTime:
+			c.Time = int64(binary.BigEndian.Uint64(data[o:]))
+		case FieldMissing:
+			c.Missing = data[o] == 1
+		}
+		o += field.byteSize()
+	}
+	return c
+}
+
 func BlockToUnix(block int64, interval int64) int64 {
 	return block * CandleSetSize * interval
 }
@@ -2,9 +2,12 @@ package candlestick
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"testing"
 )
@@ -37,6 +40,31 @@ func randomCandleSet() *CandleSet {
 	return data
 }
 
+func walkCandleSet() *CandleSet {
+	data := randomCandleSet()
+	price := 100.0
+	for i := range data.Candles {
+		if i%97 == 13 {
+			data.Candles[i] = Candle{Time: data.TimeStampAtIndex(int64(i)), Missing: true}
+			continue
+		}
+		open := price
+		price += float64(rand.Intn(21)-10) * 0.01
+		volume := rand.Intn(100000)
+		data.Candles[i] = Candle{
+			Open:           open,
+			High:           math.Max(open, price) + float64(rand.Intn(3))*0.01,
+			Low:            math.Min(open, price) - float64(rand.Intn(3))*0.01,
+			Close:          price,
+			Volume:         float64(volume) / 1000,
+			TakerVolume:    float64(rand.Intn(volume/2+1)) / 1000,
+			NumberOfTrades: rand.Int63n(300),
+			Time:           data.TimeStampAtIndex(int64(i)),
+		}
+	}
+	return data
+}
+
 func TestCandlesBinary(t *testing.T) {
 	data := randomCandleSet()
 	bin, err := EncodeCandleSet(data)
@@ -89,6 +117,35 @@ func BenchmarkCandlesDecodeBinary(b *testing.B) {
 	}
 }
 
+func BenchmarkCandlesEncodeGorilla(b *testing.B) {
+	candles := walkCandleSet()
+	var bin []byte
+	var err error
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bin, err = EncodeCandleSetWithCodec(candles, CodecGorilla)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	b.ReportMetric(float64(len(bin)), "bytes/block")
+}
+
+func BenchmarkCandlesDecodeGorilla(b *testing.B) {
+	candles := walkCandleSet()
+	bin, err := EncodeCandleSetWithCodec(candles, CodecGorilla)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err = DecodeCandleSet(bin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
+
 func BenchmarkCandlesEncodeGob(b *testing.B) {
 	candles := randomCandleSet()
 	var buf bytes.Buffer
@@ -146,3 +203,230 @@ func TestBlockToUnix(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func encodeLegacyCandleSet(b *CandleSet) []byte {
+	var metaBuf bytes.Buffer
+	err := gob.NewEncoder(&metaBuf).Encode(b.Meta)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	buf := make([]byte, 8+candleSetByteSize*len(b.Candles))
+	binary.BigEndian.PutUint64(buf[0:], uint64(len(b.Candles)))
+	for i := range b.Candles {
+		putCandle(buf[8+i*candleSetByteSize:], &b.Candles[i], CandleFieldsAll)
+	}
+	return append(buf, metaBuf.Bytes()...)
+}
+
+func TestCandlesBinaryHeader(t *testing.T) {
+	data := randomCandleSet()
+	bin, err := EncodeCandleSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	h, ok := readBlockHeader(bin, candleSetMagic)
+	if !ok || h.version != CandleSetFormatVersion || h.codec != CodecRaw || CandleField(h.fields) != CandleFieldsAll {
+		fmt.Printf("unexpected header: %v\n", h)
+		t.FailNow()
+	}
+	bin[4] = 99
+	if _, err = DecodeCandleSet(bin); err != ErrUnsupportedVersion {
+		fmt.Printf("expected unsupported version, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func TestCandlesBinaryLegacy(t *testing.T) {
+	data := randomCandleSet()
+	decoded, err := DecodeCandleSet(encodeLegacyCandleSet(data))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(decoded.Candles) != len(data.Candles) || decoded.Meta != data.Meta {
+		fmt.Printf("legacy meta did not match\n")
+		t.FailNow()
+	}
+	for i := range data.Candles {
+		if data.Candles[i] != decoded.Candles[i] {
+			fmt.Printf("legacy candle %d did not match:\n", i)
+			fmt.Println(data.Candles[i])
+			fmt.Println(decoded.Candles[i])
+			t.FailNow()
+		}
+	}
+}
+
+func TestCandlesBinaryTruncated(t *testing.T) {
+	data := randomCandleSet()
+	data.Candles = data.Candles[:10]
+	bin, err := EncodeCandleSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for n := 0; n < len(bin); n++ {
+		_, err = DecodeCandleSet(bin[:n])
+		if err == nil {
+			fmt.Printf("decoding %d of %d bytes did not fail\n", n, len(bin))
+			t.FailNow()
+		}
+	}
+	binary.BigEndian.PutUint64(bin[headerByteSize:], math.MaxUint64)
+	putChecksum(bin)
+	if _, err = DecodeCandleSet(bin); !errors.Is(err, ErrTruncated) {
+		fmt.Printf("expected truncated, got %v\n", err)
+		t.FailNow()
+	}
+	bin, _ = EncodeCandleSet(data)
+	for i := headerByteSize + 8 + 10*candleSetByteSize; i < len(bin)-checksumByteSize; i++ {
+		bin[i] = 0x7f
+	}
+	putChecksum(bin)
+	if _, err = DecodeCandleSet(bin); !errors.Is(err, ErrBadMeta) {
+		fmt.Printf("expected bad meta, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func TestCandlesBinaryChecksum(t *testing.T) {
+	data := randomCandleSet()
+	bin, err := EncodeCandleSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = VerifyBlock(bin); err != nil {
+		log.Fatalln(err)
+	}
+	bin[headerByteSize+8+3] ^= 0x01
+	if err = VerifyBlock(bin); err != ErrChecksumMismatch {
+		fmt.Printf("expected checksum mismatch, got %v\n", err)
+		t.FailNow()
+	}
+	if _, err = DecodeCandleSet(bin); err != ErrChecksumMismatch {
+		fmt.Printf("expected checksum mismatch, got %v\n", err)
+		t.FailNow()
+	}
+	if err = VerifyBlock(encodeLegacyCandleSet(data)); err != ErrNoChecksum {
+		fmt.Printf("expected no checksum, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func TestCandlesGorilla(t *testing.T) {
+	for _, data := range []*CandleSet{randomCandleSet(), walkCandleSet(), {Meta: DataSetMeta{UID: "empty"}}} {
+		data.Candles = append(data.Candles, Candle{Open: math.Inf(1), High: math.NaN(), NumberOfTrades: math.MinInt64, Time: math.MaxInt64})
+		bin, err := EncodeCandleSetWithCodec(data, CodecGorilla)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		decoded, err := DecodeCandleSet(bin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(decoded.Candles) != len(data.Candles) || decoded.Meta != data.Meta {
+			fmt.Printf("gorilla meta did not match\n")
+			t.FailNow()
+		}
+		for i := range data.Candles {
+			a, b := data.Candles[i], decoded.Candles[i]
+			if math.Float64bits(a.High) != math.Float64bits(b.High) {
+				fmt.Printf("gorilla candle %d high did not match\n", i)
+				t.FailNow()
+			}
+			a.High, b.High = 0, 0
+			if a != b {
+				fmt.Printf("gorilla candle %d did not match:\n", i)
+				fmt.Println(a)
+				fmt.Println(b)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestCandlesGorillaSmallDeltas(t *testing.T) {
+	data := walkCandleSet()
+	for i := range data.Candles {
+		close := 100.0
+		if i%2 == 1 {
+			close = math.Nextafter(close, math.Inf(1))
+		}
+		data.Candles[i] = Candle{Open: close, High: close, Low: close, Close: close, Volume: 10, Time: data.TimeStampAtIndex(int64(i))}
+	}
+	compressed, err := EncodeCandleSetWithCodec(data, CodecGorilla)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// a close flipping by one ulp takes a few bits per candle, where a
+	// full 64 bit window would take more than 64
+	if bitsPerCandle := float64(len(compressed)*8) / float64(len(data.Candles)); bitsPerCandle > 16 {
+		fmt.Printf("gorilla encoding of small deltas takes %.1f bits per candle\n", bitsPerCandle)
+		t.FailNow()
+	}
+	decoded, err := DecodeCandleSet(compressed)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for i := range data.Candles {
+		if decoded.Candles[i] != data.Candles[i] {
+			fmt.Printf("gorilla candle %d did not match\n", i)
+			t.FailNow()
+		}
+	}
+}
+
+func TestCandlesGorillaSize(t *testing.T) {
+	data := walkCandleSet()
+	raw, err := EncodeCandleSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	compressed, err := EncodeCandleSetWithCodec(data, CodecGorilla)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// decimal prices leave little to compress in the mantissa, and the
+	// random volumes none at all
+	if len(compressed)*100 > len(raw)*55 {
+		fmt.Printf("gorilla encoding too large: %d of %d bytes\n", len(compressed), len(raw))
+		t.FailNow()
+	}
+	flat := walkCandleSet()
+	for i := range flat.Candles {
+		flat.Candles[i] = Candle{Open: 1.5, High: 1.5, Low: 1.5, Close: 1.5, Time: flat.TimeStampAtIndex(int64(i))}
+	}
+	flatCompressed, err := EncodeCandleSetWithCodec(flat, CodecGorilla)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(flatCompressed)*50 > len(raw) {
+		fmt.Printf("gorilla encoding of flat data too large: %d of %d bytes\n", len(flatCompressed), len(raw))
+		t.FailNow()
+	}
+
+	// prices on a binary tick grid and whole volumes only differ in a few
+	// bits, which only XOR compression with narrow windows captures
+	grid := walkCandleSet()
+	for i := range grid.Candles {
+		c := &grid.Candles[i]
+		for _, f := range []*float64{&c.Open, &c.High, &c.Low, &c.Close} {
+			*f = math.Round(*f*64) / 64
+		}
+		c.Volume = math.Round(c.Volume)
+		c.TakerVolume = math.Round(c.TakerVolume)
+	}
+	gridCompressed, err := EncodeCandleSetWithCodec(grid, CodecGorilla)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(gridCompressed)*5 > len(raw) {
+		fmt.Printf("gorilla encoding of tick grid data too large: %d of %d bytes\n", len(gridCompressed), len(raw))
+		t.FailNow()
+	}
+	for n := 0; n < len(compressed); n += 97 {
+		if _, err = DecodeCandleSet(compressed[:n]); err == nil {
+			fmt.Printf("decoding %d of %d bytes did not fail\n", n, len(compressed))
+			t.FailNow()
+		}
+	}
+}
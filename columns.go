@@ -0,0 +1,312 @@
+package candlestick
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"math"
+)
+
+// CandleColumns holds the fields of a CandleSet as separate slices. Missing
+// is a bitmap with bit i%64 of word i/64 set when candle i is missing.
+// Columns left out when decoding are nil.
+type CandleColumns struct {
+	Open           []float64   `json:"o"`
+	High           []float64   `json:"h"`
+	Low            []float64   `json:"l"`
+	Close          []float64   `json:"c"`
+	Volume         []float64   `json:"v"`
+	TakerVolume    []float64   `json:"tv"`
+	NumberOfTrades []int64     `json:"not"`
+	Time           []int64     `json:"t"`
+	Missing        []uint64    `json:"m"`
+	Meta           DataSetMeta `json:"meta"`
+	length         int
+}
+
+func NewCandleColumns(b *CandleSet) *CandleColumns {
+	n := len(b.Candles)
+	c := &CandleColumns{
+		Open:           make([]float64, n),
+		High:           make([]float64, n),
+		Low:            make([]float64, n),
+		Close:          make([]float64, n),
+		Volume:         make([]float64, n),
+		TakerVolume:    make([]float64, n),
+		NumberOfTrades: make([]int64, n),
+		Time:           make([]int64, n),
+		Missing:        make([]uint64, bitmapWords(n)),
+		Meta:           b.Meta,
+	}
+	for i, candle := range b.Candles {
+		c.Open[i] = candle.Open
+		c.High[i] = candle.High
+		c.Low[i] = candle.Low
+		c.Close[i] = candle.Close
+		c.Volume[i] = candle.Volume
+		c.TakerVolume[i] = candle.TakerVolume
+		c.NumberOfTrades[i] = candle.NumberOfTrades
+		c.Time[i] = candle.Time
+		c.SetMissing(i, candle.Missing)
+	}
+	return c
+}
+
+// Len returns the number of candles, taken from the first column present.
+func (c *CandleColumns) Len() int {
+	for _, col := range [][]float64{c.Open, c.High, c.Low, c.Close, c.Volume, c.TakerVolume} {
+		if col != nil {
+			return len(col)
+		}
+	}
+	for _, col := range [][]int64{c.NumberOfTrades, c.Time} {
+		if col != nil {
+			return len(col)
+		}
+	}
+	return c.length
+}
+
+func (c *CandleColumns) IsMissing(i int) bool {
+	if i/64 >= len(c.Missing) {
+		return false
+	}
+	return c.Missing[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (c *CandleColumns) SetMissing(i int, missing bool) {
+	if missing {
+		c.Missing[i/64] |= 1 << uint(i%64)
+	} else {
+		c.Missing[i/64] &^= 1 << uint(i%64)
+	}
+}
+
+func (c *CandleColumns) Candle(i int) Candle {
+	return Candle{
+		Open:           floatAt(c.Open, i),
+		High:           floatAt(c.High, i),
+		Low:            floatAt(c.Low, i),
+		Close:          floatAt(c.Close, i),
+		Volume:         floatAt(c.Volume, i),
+		TakerVolume:    floatAt(c.TakerVolume, i),
+		NumberOfTrades: intAt(c.NumberOfTrades, i),
+		Time:           intAt(c.Time, i),
+		Missing:        c.IsMissing(i),
+	}
+}
+
+func (c *CandleColumns) CandleSet() *CandleSet {
+	candles := make([]Candle, c.Len())
+	for i := range candles {
+		candles[i] = c.Candle(i)
+	}
+	return &CandleSet{
+		Candles: candles,
+		Meta:    c.Meta,
+	}
+}
+
+func floatAt(col []float64, i int) float64 {
+	if col == nil {
+		return 0
+	}
+	return col[i]
+}
+
+func intAt(col []int64, i int) int64 {
+	if col == nil {
+		return 0
+	}
+	return col[i]
+}
+
+func bitmapWords(n int) int {
+	return (n + 63) / 64
+}
+
+func columnByteSize(field CandleField, n int) int {
+	if field == FieldMissing {
+		return 8 * bitmapWords(n)
+	}
+	return 8 * n
+}
+
+func (c *CandleColumns) floatColumn(field CandleField) *[]float64 {
+	switch field {
+	case FieldOpen:
+		return &c.Open
+	case FieldHigh:
+		return &c.High
+	case FieldLow:
+		return &c.Low
+	case FieldClose:
+		return &c.Close
+	case FieldVolume:
+		return &c.Volume
+	case FieldTakerVolume:
+		return &c.TakerVolume
+	}
+	return nil
+}
+
+func (c *CandleColumns) intColumn(field CandleField) *[]int64 {
+	switch field {
+	case FieldNumberOfTrades:
+		return &c.NumberOfTrades
+	case FieldTime:
+		return &c.Time
+	}
+	return nil
+}
+
+func EncodeCandleColumns(c *CandleColumns) ([]byte, error) {
+
+	// encode meta data
+	var metaBuf bytes.Buffer
+	err := gob.NewEncoder(&metaBuf).Encode(c.Meta)
+	if err != nil {
+		return nil, err
+	}
+	metaBytes := metaBuf.Bytes()
+
+	// create main buffer
+	buf := make([]byte, headerByteSize, headerByteSize+8+candleSetByteSize*c.Len()+len(metaBytes)+checksumByteSize)
+
+	// add header
+	putBlockHeader(buf, candleSetMagic, blockHeader{
+		version: formatVersion1,
+		codec:   CodecColumnar,
+		flags:   flagChecksum,
+		fields:  uint16(CandleFieldsAll),
+	})
+
+	// add candle data
+	buf = appendCandleColumns(buf, c)
+
+	// copy meta bytes
+	buf = append(buf, metaBytes...)
+
+	// add checksum
+	buf = append(buf, make([]byte, checksumByteSize)...)
+	putChecksum(buf)
+
+	return buf, nil
+}
+
+// CodecColumnar stores each field as a contiguous column, in record field
+// order, so a single column can be decoded without touching the others:
+//
+//	count   uint64
+//	columns 8 bytes per value, Missing as bitmap words
+//	meta    gob
+func appendCandleColumns(buf []byte, c *CandleColumns) []byte {
+	n := c.Len()
+	buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	for _, field := range candleFieldOrder {
+		switch {
+		case field == FieldMissing:
+			for i := 0; i < bitmapWords(n); i++ {
+				word := uint64(0)
+				if i < len(c.Missing) {
+					word = c.Missing[i]
+				}
+				buf = binary.BigEndian.AppendUint64(buf, word)
+			}
+		case c.floatColumn(field) != nil:
+			col := *c.floatColumn(field)
+			for i := 0; i < n; i++ {
+				buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(floatAt(col, i)))
+			}
+		default:
+			col := *c.intColumn(field)
+			for i := 0; i < n; i++ {
+				buf = binary.BigEndian.AppendUint64(buf, uint64(intAt(col, i)))
+			}
+		}
+	}
+	return buf
+}
+
+// DecodeCandleColumns decodes the requested fields of a columnar encoded
+// block. Only the requested columns are read; the others are left nil.
+func DecodeCandleColumns(data []byte, fields CandleField) (*CandleColumns, error) {
+	h, ok := readBlockHeader(data, candleSetMagic)
+	if !ok || h.codec != CodecColumnar {
+		return nil, ErrUnsupportedCodec
+	}
+	if h.version != formatVersion1 {
+		return nil, ErrUnsupportedVersion
+	}
+	payload, err := blockPayload(data, h)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCandleColumns(payload, CandleField(h.fields), fields)
+}
+
+func decodeCandleColumns(data []byte, stored CandleField, fields CandleField) (*CandleColumns, error) {
+
+	if stored == 0 || stored&^CandleFieldsAll != 0 {
+		return nil, ErrCorruptHeader
+	}
+	if len(data) < 8 {
+		return nil, ErrTruncated
+	}
+
+	// check that all stored columns fit
+	count := binary.BigEndian.Uint64(data[0:])
+	if count > uint64(len(data)) {
+		return nil, ErrTruncated
+	}
+	n := int(count)
+	size := 8
+	for _, field := range candleFieldOrder {
+		if stored&field != 0 {
+			size += columnByteSize(field, n)
+		}
+	}
+	if size > len(data) {
+		return nil, ErrTruncated
+	}
+
+	// decode requested columns
+	c := &CandleColumns{length: n}
+	o := 8
+	for _, field := range candleFieldOrder {
+		if stored&field == 0 {
+			continue
+		}
+		size := columnByteSize(field, n)
+		if fields&field != 0 {
+			col := data[o : o+size]
+			switch {
+			case field == FieldMissing:
+				c.Missing = make([]uint64, bitmapWords(n))
+				for i := range c.Missing {
+					c.Missing[i] = binary.BigEndian.Uint64(col[8*i:])
+				}
+			case c.floatColumn(field) != nil:
+				values := make([]float64, n)
+				for i := range values {
+					values[i] = math.Float64frombits(binary.BigEndian.Uint64(col[8*i:]))
+				}
+				*c.floatColumn(field) = values
+			default:
+				values := make([]int64, n)
+				for i := range values {
+					values[i] = int64(binary.BigEndian.Uint64(col[8*i:]))
+				}
+				*c.intColumn(field) = values
+			}
+		}
+		o += size
+	}
+
+	err := decodeMeta(data[o:], &c.Meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
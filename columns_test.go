@@ -0,0 +1,81 @@
+package candlestick
+
+import (
+	"fmt"
+	"log"
+	"testing"
+)
+
+func TestCandleColumns(t *testing.T) {
+	data := walkCandleSet()
+	converted := NewCandleColumns(data).CandleSet()
+	if converted.Meta != data.Meta || len(converted.Candles) != len(data.Candles) {
+		fmt.Printf("columns meta did not match\n")
+		t.FailNow()
+	}
+	for i := range data.Candles {
+		if converted.Candles[i] != data.Candles[i] {
+			fmt.Printf("columns candle %d did not match:\n", i)
+			fmt.Println(data.Candles[i])
+			fmt.Println(converted.Candles[i])
+			t.FailNow()
+		}
+	}
+}
+
+func TestCandleColumnsBinary(t *testing.T) {
+	data := walkCandleSet()
+	bin, err := EncodeCandleColumns(NewCandleColumns(data))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// single column
+	closes, err := DecodeCandleColumns(bin, FieldClose|FieldMissing)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if closes.Open != nil || closes.Time != nil || closes.Len() != len(data.Candles) || closes.Meta != data.Meta {
+		fmt.Printf("unexpected columns decoded\n")
+		t.FailNow()
+	}
+	for i := range data.Candles {
+		if closes.Close[i] != data.Candles[i].Close || closes.IsMissing(i) != data.Candles[i].Missing {
+			fmt.Printf("close %d did not match\n", i)
+			t.FailNow()
+		}
+	}
+
+	// whole set through the generic decoder
+	decoded, err := DecodeCandleSet(bin)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for i := range data.Candles {
+		if decoded.Candles[i] != data.Candles[i] {
+			fmt.Printf("columnar candle %d did not match\n", i)
+			t.FailNow()
+		}
+	}
+
+	for n := 0; n < len(bin); n += 101 {
+		if _, err = DecodeCandleColumns(bin[:n], CandleFieldsAll); err == nil {
+			fmt.Printf("decoding %d of %d bytes did not fail\n", n, len(bin))
+			t.FailNow()
+		}
+	}
+}
+
+func BenchmarkCandleColumnsDecodeClose(b *testing.B) {
+	bin, err := EncodeCandleSetWithCodec(randomCandleSet(), CodecColumnar)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err = DecodeCandleColumns(bin, FieldClose)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package candlestick
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Encoded blocks start with a fixed header:
+//
+//	magic   [4]byte
+//	version uint8
+//	codec   uint8
+//	flags   uint16
+//	fields  uint16
+//
+// Blobs written before the header existed start directly with a uint64
+// record count. Such a count never has a non-zero first byte, so a blob
+// that does not start with the magic is read as legacy version 0.
+const headerByteSize = 10
+
+var candleSetMagic = [4]byte{'G', 'D', 'C', 'S'}
+var indicatorMagic = [4]byte{'G', 'D', 'I', 'S'}
+
+// When flagChecksum is set, the block ends with a CRC32C of all
+// preceding bytes, header included.
+const (
+	flagChecksum = uint16(1 << 0)
+)
+
+const checksumByteSize = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+const (
+	formatVersionLegacy = uint8(0)
+	formatVersion1      = uint8(1)
+	formatVersion2      = uint8(2)
+	formatVersion3      = uint8(3)
+	formatVersion4      = uint8(4)
+)
+
+const CandleSetFormatVersion = formatVersion1
+const IndicatorFormatVersion = formatVersion4
+
+var ErrUnsupportedVersion = errors.New("candlestick: unsupported format version")
+var ErrUnsupportedCodec = errors.New("candlestick: unsupported codec")
+var ErrTruncated = errors.New("candlestick: truncated data")
+var ErrCorruptHeader = errors.New("candlestick: corrupt header")
+var ErrBadMeta = errors.New("candlestick: bad meta data")
+var ErrCorruptData = errors.New("candlestick: corrupt data")
+var ErrChecksumMismatch = errors.New("candlestick: checksum mismatch")
+var ErrNoChecksum = errors.New("candlestick: block has no checksum")
+var ErrFieldTooLong = errors.New("candlestick: field too long for format")
+var ErrUnrepresentable = errors.New("candlestick: value not representable in series storage")
+
+type Codec uint8
+
+const (
+	CodecRaw      = Codec(0)
+	CodecGorilla  = Codec(1)
+	CodecColumnar = Codec(2)
+)
+
+type CandleField uint16
+
+const (
+	FieldOpen CandleField = 1 << iota
+	FieldHigh
+	FieldLow
+	FieldClose
+	FieldVolume
+	FieldTakerVolume
+	FieldNumberOfTrades
+	FieldTime
+	FieldMissing
+)
+
+const CandleFieldsAll = FieldOpen | FieldHigh | FieldLow | FieldClose | FieldVolume |
+	FieldTakerVolume | FieldNumberOfTrades | FieldTime | FieldMissing
+
+// candleFieldOrder is the order in which fields appear in a record.
+var candleFieldOrder = []CandleField{
+	FieldOpen,
+	FieldHigh,
+	FieldLow,
+	FieldClose,
+	FieldVolume,
+	FieldTakerVolume,
+	FieldNumberOfTrades,
+	FieldTime,
+	FieldMissing,
+}
+
+func (f CandleField) byteSize() int {
+	if f == FieldMissing {
+		return 1
+	}
+	return 8
+}
+
+func (f CandleField) recordSize() int {
+	size := 0
+	for _, field := range candleFieldOrder {
+		if f&field != 0 {
+			size += field.byteSize()
+		}
+	}
+	return size
+}
+
+type blockHeader struct {
+	version uint8
+	codec   Codec
+	flags   uint16
+	fields  uint16
+}
+
+func putBlockHeader(buf []byte, magic [4]byte, h blockHeader) {
+	copy(buf[0:], magic[:])
+	buf[4] = h.version
+	buf[5] = uint8(h.codec)
+	binary.BigEndian.PutUint16(buf[6:], h.flags)
+	binary.BigEndian.PutUint16(buf[8:], h.fields)
+}
+
+// readBlockHeader returns false when data does not start with magic,
+// meaning the blob uses the legacy headerless layout.
+func readBlockHeader(data []byte, magic [4]byte) (blockHeader, bool) {
+	if len(data) < headerByteSize || [4]byte(data[0:4]) != magic {
+		return blockHeader{version: formatVersionLegacy}, false
+	}
+	return blockHeader{
+		version: data[4],
+		codec:   Codec(data[5]),
+		flags:   binary.BigEndian.Uint16(data[6:]),
+		fields:  binary.BigEndian.Uint16(data[8:]),
+	}, true
+}
+
+// readCount reads the leading record count of a payload and checks that
+// data holds at least that many records of recordSize bytes.
+func readCount(data []byte, recordSize int) (int, error) {
+	if len(data) < 8 {
+		return 0, ErrTruncated
+	}
+	if recordSize <= 0 {
+		return 0, ErrCorruptHeader
+	}
+	count := binary.BigEndian.Uint64(data[0:])
+	if count > uint64((len(data)-8)/recordSize) {
+		return 0, ErrTruncated
+	}
+	return int(count), nil
+}
+
+func decodeMeta(data []byte, meta any) error {
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(meta)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadMeta, err)
+	}
+	return nil
+}
+
+// putChecksum fills the last bytes of buf with the checksum of the rest.
+func putChecksum(buf []byte) {
+	end := len(buf) - checksumByteSize
+	binary.BigEndian.PutUint32(buf[end:], crc32.Checksum(buf[:end], castagnoli))
+}
+
+// blockPayload returns the bytes between the header and the optional
+// trailing checksum, verifying the checksum when the header declares one.
+func blockPayload(data []byte, h blockHeader) ([]byte, error) {
+	payload, err := unverifiedBlockPayload(data, h)
+	if err != nil || h.flags&flagChecksum == 0 {
+		return payload, err
+	}
+	end := len(data) - checksumByteSize
+	if crc32.Checksum(data[:end], castagnoli) != binary.BigEndian.Uint32(data[end:]) {
+		return nil, ErrChecksumMismatch
+	}
+	return payload, nil
+}
+
+func unverifiedBlockPayload(data []byte, h blockHeader) ([]byte, error) {
+	if h.flags&flagChecksum == 0 {
+		return data[headerByteSize:], nil
+	}
+	if len(data) < headerByteSize+checksumByteSize {
+		return nil, ErrTruncated
+	}
+	return data[headerByteSize : len(data)-checksumByteSize], nil
+}
+
+// VerifyBlock checks the integrity of an encoded candle or indicator block
+// without decoding it. Blocks written without a checksum, including all
+// legacy blocks, return ErrNoChecksum.
+func VerifyBlock(data []byte) error {
+	h, ok := readBlockHeader(data, candleSetMagic)
+	if !ok {
+		h, ok = readBlockHeader(data, indicatorMagic)
+	}
+	if !ok || h.flags&flagChecksum == 0 {
+		return ErrNoChecksum
+	}
+	_, err := blockPayload(data, h)
+	return err
+}
+
+// byteReader reads consecutive fields from a payload. Reading past the
+// end sets err and yields zero values, so callers only need to check err
+// once.
+type byteReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *byteReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data)-r.pos {
+		r.err = ErrTruncated
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *byteReader) uint8() uint8 {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *byteReader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *byteReader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// string reads a uint16 length prefixed string.
+func (r *byteReader) string() string {
+	return string(r.bytes(int(r.uint16())))
+}
@@ -0,0 +1,158 @@
+package candlestick
+
+import (
+	"errors"
+	"sort"
+)
+
+type GapKind uint8
+
+const (
+	// GapMissing is a run of candles marked missing, including the
+	// candles past the end of an incomplete block that is followed by
+	// later blocks.
+	GapMissing GapKind = iota
+	// GapMisaligned is a run of candles whose Time does not match their
+	// position in the block.
+	GapMisaligned
+	// GapAbsentBlock is a run of blocks that do not exist at all.
+	GapAbsentBlock
+)
+
+func (k GapKind) String() string {
+	switch k {
+	case GapMissing:
+		return "MISSING"
+	case GapMisaligned:
+		return "MISALIGNED"
+	case GapAbsentBlock:
+		return "ABSENT_BLOCK"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Gap is a contiguous run of candles of one kind, covering the time stamps
+// in [From, To).
+type Gap struct {
+	Kind  GapKind `json:"kind"`
+	From  int64   `json:"from"`
+	To    int64   `json:"to"`
+	Count int64   `json:"count"`
+}
+
+// GapReport lists the gaps in the history of one symbol and interval,
+// ordered by time. Adjacent gaps of the same kind are merged, also across
+// block boundaries, so each gap can be backfilled as a single range.
+type GapReport struct {
+	Symbol   string `json:"symbol"`
+	Interval int64  `json:"interval"`
+	Gaps     []Gap  `json:"gaps"`
+}
+
+// MissingCandles returns the number of candles covered by all gaps.
+func (r *GapReport) MissingCandles() int64 {
+	var n int64
+	for _, g := range r.Gaps {
+		n += g.Count
+	}
+	return n
+}
+
+func (r *GapReport) add(kind GapKind, from int64, to int64) {
+	if n := len(r.Gaps); n > 0 && r.Gaps[n-1].Kind == kind && r.Gaps[n-1].To == from {
+		r.Gaps[n-1].To = to
+		r.Gaps[n-1].Count += (to - from) / r.Interval
+		return
+	}
+	r.Gaps = append(r.Gaps, Gap{
+		Kind:  kind,
+		From:  from,
+		To:    to,
+		Count: (to - from) / r.Interval,
+	})
+}
+
+// FindGaps scans blocks of one symbol and interval for gaps. Blocks between
+// the first and last given block that are not given are reported absent.
+// The last block may be incomplete without its remainder being reported.
+func FindGaps(sets []*CandleSet) (*GapReport, error) {
+
+	if len(sets) == 0 {
+		return &GapReport{}, nil
+	}
+
+	// validate blocks
+	sorted := make([]*CandleSet, len(sets))
+	copy(sorted, sets)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Meta.Block < sorted[j].Meta.Block
+	})
+	for i, cs := range sorted {
+		if cs.Meta.Interval != sorted[0].Meta.Interval || cs.Meta.Symbol != sorted[0].Meta.Symbol {
+			return nil, ErrMetaMismatch
+		}
+		if i > 0 && cs.Meta.Block == sorted[i-1].Meta.Block {
+			return nil, ErrMetaMismatch
+		}
+	}
+
+	report := &GapReport{
+		Symbol:   sorted[0].Meta.Symbol,
+		Interval: sorted[0].Meta.Interval,
+	}
+	for i, cs := range sorted {
+		if i > 0 {
+			for block := sorted[i-1].Meta.Block + 1; block < cs.Meta.Block; block++ {
+				report.addAbsentBlock(block)
+			}
+		}
+		report.scanBlock(cs, i == len(sorted)-1)
+	}
+	return report, nil
+}
+
+// ScanGaps fetches the blocks in [fromBlock, toBlock] from source one at a
+// time and reports their gaps, so that a long history can be scanned
+// without holding it in memory.
+func ScanGaps(source BlockSource, symbol AssetIdentifier, interval int64, fromBlock int64, toBlock int64) (*GapReport, error) {
+	report := &GapReport{
+		Symbol:   symbol.Symbol,
+		Interval: interval,
+	}
+	for block := fromBlock; block <= toBlock; block++ {
+		cs, err := source.GetCandleSet(symbol, interval, block)
+		if errors.Is(err, ErrBlockNotFound) {
+			report.addAbsentBlock(block)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if cs.Meta.Block != block || cs.Meta.Interval != interval {
+			return nil, ErrMetaMismatch
+		}
+		report.scanBlock(cs, block == toBlock)
+	}
+	return report, nil
+}
+
+func (r *GapReport) addAbsentBlock(block int64) {
+	from := BlockToUnix(block, r.Interval)
+	r.add(GapAbsentBlock, from, from+CandleSetSize*r.Interval)
+}
+
+func (r *GapReport) scanBlock(cs *CandleSet, last bool) {
+	for i := range cs.Candles {
+		ts := cs.TimeStampAtIndex(int64(i))
+		switch {
+		case cs.Candles[i].Missing:
+			r.add(GapMissing, ts, ts+r.Interval)
+		case cs.Candles[i].Time != ts:
+			r.add(GapMisaligned, ts, ts+r.Interval)
+		}
+	}
+	if !last && int64(len(cs.Candles)) < CandleSetSize {
+		r.add(GapMissing, cs.TimeStampAtIndex(int64(len(cs.Candles))), cs.TimeStampAtIndex(CandleSetSize))
+	}
+}
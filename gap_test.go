@@ -0,0 +1,86 @@
+package candlestick
+
+import (
+	"fmt"
+	"log"
+	"testing"
+)
+
+func TestFindGaps(t *testing.T) {
+	sets := walkBlocks(Interval1m, 0, 1, 3, 4)
+	for _, cs := range sets {
+		for i := range cs.Candles {
+			cs.Candles[i].Missing = false
+		}
+	}
+
+	// missing run across the boundary of block 0 and 1
+	for i := CandleSetSize - 3; i < CandleSetSize; i++ {
+		sets[0].Candles[i].Missing = true
+	}
+	sets[1].Candles[0].Missing = true
+	sets[1].Candles[10].Time++
+	sets[1].Candles[11].Time = 0
+
+	// incomplete blocks
+	sets[1].Candles = sets[1].Candles[:100]
+	sets[3].Candles = sets[3].Candles[:100]
+
+	report, err := FindGaps([]*CandleSet{sets[3], sets[1], sets[0], sets[2]})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	expected := []Gap{
+		{Kind: GapMissing, From: sets[0].TimeStampAtIndex(CandleSetSize - 3), To: sets[1].TimeStampAtIndex(1), Count: 4},
+		{Kind: GapMisaligned, From: sets[1].TimeStampAtIndex(10), To: sets[1].TimeStampAtIndex(12), Count: 2},
+		{Kind: GapMissing, From: sets[1].TimeStampAtIndex(100), To: BlockToUnix(2, Interval1m), Count: CandleSetSize - 100},
+		{Kind: GapAbsentBlock, From: BlockToUnix(2, Interval1m), To: BlockToUnix(3, Interval1m), Count: CandleSetSize},
+	}
+	if len(report.Gaps) != len(expected) {
+		fmt.Printf("unexpected gaps: %v\n", report.Gaps)
+		t.FailNow()
+	}
+	for i, g := range report.Gaps {
+		if g != expected[i] {
+			fmt.Printf("gap %d: expected %v, got %v\n", i, expected[i], g)
+			t.FailNow()
+		}
+	}
+	if report.MissingCandles() != 4+2+CandleSetSize-100+CandleSetSize {
+		fmt.Printf("unexpected number of missing candles %d\n", report.MissingCandles())
+		t.FailNow()
+	}
+
+	// the same report is built from a block source
+	blocks := map[int64]*CandleSet{}
+	for _, cs := range sets {
+		blocks[cs.Meta.Block] = cs
+	}
+	source := BlockSourceFunc(func(symbol AssetIdentifier, interval int64, block int64) (*CandleSet, error) {
+		if cs, ok := blocks[block]; ok {
+			return cs, nil
+		}
+		return nil, ErrBlockNotFound
+	})
+	symbol := NewAssetIdentifier("broker", "exchange", sets[0].Meta.Symbol)
+	scanned, err := ScanGaps(source, symbol, Interval1m, 0, 4)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(scanned.Gaps) != len(expected) {
+		fmt.Printf("unexpected scanned gaps: %v\n", scanned.Gaps)
+		t.FailNow()
+	}
+	for i, g := range scanned.Gaps {
+		if g != expected[i] {
+			fmt.Printf("scanned gap %d: expected %v, got %v\n", i, expected[i], g)
+			t.FailNow()
+		}
+	}
+
+	sets[2].Meta.Interval = Interval5m
+	if _, err = FindGaps(sets); err != ErrMetaMismatch {
+		fmt.Printf("expected meta mismatch, got %v\n", err)
+		t.FailNow()
+	}
+}
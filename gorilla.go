@@ -0,0 +1,272 @@
+package candlestick
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// CodecGorilla stores the candles column by column in a single bit stream,
+// following the compression scheme of Facebook's Gorilla time series
+// database: Time as delta-of-delta, the float fields XOR-ed against a
+// predicted value, NumberOfTrades as deltas and Missing as one bit per
+// candle. The payload is laid out as
+//
+//	count  uint64
+//	size   uint64
+//	stream [size]byte
+//	meta   gob
+const gorillaColumns = 9
+
+type gorillaColumn struct {
+	field func(c *Candle) *float64
+	// reference predicts the value at i from columns that come earlier
+	// in the stream, or from earlier candles of the same column
+	reference func(candles []Candle, i int) float64
+}
+
+// Close comes first, as the previous close predicts the open and the
+// candle body predicts the wicks.
+var gorillaFloatColumns = []gorillaColumn{
+	{
+		field:     func(c *Candle) *float64 { return &c.Close },
+		reference: previous(func(c *Candle) *float64 { return &c.Close }),
+	},
+	{
+		field:     func(c *Candle) *float64 { return &c.Open },
+		reference: previous(func(c *Candle) *float64 { return &c.Close }),
+	},
+	{
+		field:     func(c *Candle) *float64 { return &c.High },
+		reference: func(candles []Candle, i int) float64 { return math.Max(candles[i].Open, candles[i].Close) },
+	},
+	{
+		field:     func(c *Candle) *float64 { return &c.Low },
+		reference: func(candles []Candle, i int) float64 { return math.Min(candles[i].Open, candles[i].Close) },
+	},
+	{
+		field:     func(c *Candle) *float64 { return &c.Volume },
+		reference: previous(func(c *Candle) *float64 { return &c.Volume }),
+	},
+	{
+		field:     func(c *Candle) *float64 { return &c.TakerVolume },
+		reference: previous(func(c *Candle) *float64 { return &c.TakerVolume }),
+	},
+}
+
+func previous(field func(c *Candle) *float64) func(candles []Candle, i int) float64 {
+	return func(candles []Candle, i int) float64 {
+		if i == 0 {
+			return 0
+		}
+		return *field(&candles[i-1])
+	}
+}
+
+func appendCandlesGorilla(buf []byte, candles []Candle) []byte {
+	w := &bitWriter{buf: make([]byte, 0, len(candles)*8)}
+
+	// time column
+	var prevTime, prevDelta int64
+	for i := range candles {
+		t := candles[i].Time
+		if i == 0 {
+			w.writeBits(uint64(t), 64)
+		} else {
+			delta := t - prevTime
+			writeVarBits(w, zigzag(delta-prevDelta))
+			prevDelta = delta
+		}
+		prevTime = t
+	}
+
+	// float columns
+	for _, column := range gorillaFloatColumns {
+		var s xorState
+		for i := range candles {
+			s.write(w, math.Float64bits(*column.field(&candles[i])), math.Float64bits(column.reference(candles, i)))
+		}
+	}
+
+	// number of trades column
+	var prevTrades int64
+	for i := range candles {
+		writeVarBits(w, zigzag(candles[i].NumberOfTrades-prevTrades))
+		prevTrades = candles[i].NumberOfTrades
+	}
+
+	// missing column
+	for i := range candles {
+		w.writeBit(candles[i].Missing)
+	}
+
+	stream := w.flush()
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(candles)))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(stream)))
+	return append(buf, stream...)
+}
+
+func decodeCandleSetGorilla(data []byte) (*CandleSet, error) {
+
+	if len(data) < 16 {
+		return nil, ErrTruncated
+	}
+	count := binary.BigEndian.Uint64(data[0:])
+	size := binary.BigEndian.Uint64(data[8:])
+	if size > uint64(len(data)-16) {
+		return nil, ErrTruncated
+	}
+
+	// every candle takes at least one bit in each column
+	if count > size*8/gorillaColumns {
+		return nil, ErrTruncated
+	}
+	numberOfCandles := int(count)
+	candles := make([]Candle, numberOfCandles)
+	r := &bitReader{data: data[16 : 16+size]}
+
+	// time column
+	var t, delta int64
+	for i := range candles {
+		if i == 0 {
+			t = int64(r.readBits(64))
+		} else {
+			delta += unzigzag(readVarBits(r))
+			t += delta
+		}
+		candles[i].Time = t
+	}
+
+	// float columns
+	for _, column := range gorillaFloatColumns {
+		var s xorState
+		for i := range candles {
+			ref := math.Float64bits(column.reference(candles, i))
+			*column.field(&candles[i]) = math.Float64frombits(s.read(r, ref))
+		}
+	}
+
+	// number of trades column
+	var trades int64
+	for i := range candles {
+		trades += unzigzag(readVarBits(r))
+		candles[i].NumberOfTrades = trades
+	}
+
+	// missing column
+	for i := range candles {
+		candles[i].Missing = r.readBit()
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var meta DataSetMeta
+	err := decodeMeta(data[16+size:], &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &CandleSet{
+		Candles: candles,
+		Meta:    meta,
+	}
+
+	return cs, nil
+}
+
+// xorState tracks the meaningful bit window of a XOR-compressed column.
+// A column starts without a window, so the first non-zero XOR always
+// stores its own.
+type xorState struct {
+	valid    bool
+	leading  int
+	trailing int
+}
+
+func (s *xorState) write(w *bitWriter, v uint64, ref uint64) {
+	x := v ^ ref
+	if x == 0 {
+		w.writeBit(false)
+		return
+	}
+	w.writeBit(true)
+
+	leading := bits.LeadingZeros64(x)
+	trailing := bits.TrailingZeros64(x)
+
+	// reuse the previous window when the meaningful bits fit inside it
+	if s.valid && leading >= s.leading && trailing >= s.trailing {
+		w.writeBit(false)
+		w.writeBits(x>>uint(s.trailing), 64-s.leading-s.trailing)
+		return
+	}
+
+	w.writeBit(true)
+	s.valid, s.leading, s.trailing = true, leading, trailing
+	significant := 64 - leading - trailing
+	w.writeBits(uint64(leading), 6)
+	w.writeBits(uint64(significant-1), 6)
+	w.writeBits(x>>uint(trailing), significant)
+}
+
+func (s *xorState) read(r *bitReader, ref uint64) uint64 {
+	if !r.readBit() {
+		return ref
+	}
+	if r.readBit() {
+		leading := int(r.readBits(6))
+		significant := int(r.readBits(6)) + 1
+		if leading+significant > 64 {
+			r.err = ErrCorruptData
+			return 0
+		}
+		s.valid, s.leading, s.trailing = true, leading, 64-leading-significant
+	} else if !s.valid {
+		r.err = ErrCorruptData
+		return 0
+	}
+	return ref ^ r.readBits(64-s.leading-s.trailing)<<uint(s.trailing)
+}
+
+// writeVarBits writes v behind a unary prefix selecting its bit width.
+func writeVarBits(w *bitWriter, v uint64) {
+	switch {
+	case v == 0:
+		w.writeBits(0b0, 1)
+	case v < 1<<7:
+		w.writeBits(0b10, 2)
+		w.writeBits(v, 7)
+	case v < 1<<9:
+		w.writeBits(0b110, 3)
+		w.writeBits(v, 9)
+	case v < 1<<12:
+		w.writeBits(0b1110, 4)
+		w.writeBits(v, 12)
+	case v < 1<<32:
+		w.writeBits(0b11110, 5)
+		w.writeBits(v, 32)
+	default:
+		w.writeBits(0b11111, 5)
+		w.writeBits(v, 64)
+	}
+}
+
+var varBitWidths = []int{0, 7, 9, 12, 32, 64}
+
+func readVarBits(r *bitReader) uint64 {
+	prefix := 0
+	for prefix < len(varBitWidths)-1 && r.readBit() {
+		prefix++
+	}
+	return r.readBits(varBitWidths[prefix])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"encoding/gob"
 	"math"
+	"sort"
 )
 
 type SeriesType string
@@ -35,9 +36,11 @@ type IndicatorValue struct {
 const seriesByteSize = int(CandleSetSize)*indicatorValueByteSize + 3*seriesFieldByteSize // includes name
 
 type IndicatorSeries struct {
-	Values []IndicatorValue `json:"values"`
-	Kind   SeriesType       `json:"kind"`
-	Axis   AxisType         `json:"axis"`
+	Values  []IndicatorValue `json:"values"`
+	Kind    SeriesType       `json:"kind"`
+	Axis    AxisType         `json:"axis"`
+	Storage SeriesStorage    `json:"storage,omitempty"`
+	Scale   uint8            `json:"scale,omitempty"`
 }
 
 func (b *Indicator) UID() string {
@@ -88,6 +91,52 @@ func (b *Indicator) UnixLast() int64 {
 	return (b.Meta.Block+1)*b.Meta.Interval*CandleSetSize - b.Meta.Interval
 }
 
+// SeriesKeys returns the series names in sorted order, which is the order
+// in which they are encoded.
+func (b *Indicator) SeriesKeys() []string {
+	keys := make([]string, 0, len(b.Series))
+	for key := range b.Series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Equal reports whether both indicators hold the same meta data and series.
+// Values of missing entries are ignored and NaN values are equal to each
+// other.
+func (b *Indicator) Equal(o *Indicator) bool {
+	if !b.Meta.Equal(&o.Meta) || len(b.Series) != len(o.Series) {
+		return false
+	}
+	for key, series := range b.Series {
+		other, ok := o.Series[key]
+		if !ok || !series.Equal(other) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *IndicatorSeries) Equal(o *IndicatorSeries) bool {
+	if s.Kind != o.Kind || s.Axis != o.Axis || len(s.Values) != len(o.Values) {
+		return false
+	}
+	for i, v := range s.Values {
+		if !v.Equal(o.Values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v IndicatorValue) Equal(o IndicatorValue) bool {
+	if v.Missing || o.Missing {
+		return v.Missing == o.Missing
+	}
+	return v.Value == o.Value || math.IsNaN(v.Value) && math.IsNaN(o.Value)
+}
+
 type IndicatorMeta struct {
 	UID          string `json:"uid"`
 	Block        int64  `json:"block"`
@@ -100,6 +149,20 @@ type IndicatorMeta struct {
 	Parameters   []int  `json:"parameters"`
 }
 
+func (m *IndicatorMeta) Equal(o *IndicatorMeta) bool {
+	if m.UID != o.UID || m.Block != o.Block || m.Complete != o.Complete || m.LastUpdate != o.LastUpdate ||
+		m.Symbol != o.Symbol || m.Interval != o.Interval || m.BaseInterval != o.BaseInterval ||
+		m.Name != o.Name || len(m.Parameters) != len(o.Parameters) {
+		return false
+	}
+	for i, p := range m.Parameters {
+		if p != o.Parameters[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func EncodeIndicatorSet(ind *Indicator) ([]byte, error) {
 
 	// encode meta data
@@ -110,6 +173,63 @@ func EncodeIndicatorSet(ind *Indicator) ([]byte, error) {
 	}
 	metaBytes := metaBuf.Bytes()
 
+	// create main buffer
+	size := headerByteSize + 8 + len(metaBytes) + checksumByteSize
+	for key, series := range ind.Series {
+		size += 3*2 + len(key) + len(series.Kind) + len(series.Axis) + 2 + 8 + len(series.Values)*indicatorValueByteSize
+	}
+	buf := make([]byte, headerByteSize, size)
+
+	// add header
+	putBlockHeader(buf, indicatorMagic, blockHeader{
+		version: formatVersion4,
+		codec:   CodecRaw,
+		flags:   flagChecksum,
+	})
+
+	// add number of series
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(ind.Series)))
+
+	// add each series
+	for _, key := range ind.SeriesKeys() {
+		series := ind.Series[key]
+		for _, text := range []string{key, string(series.Kind), string(series.Axis)} {
+			if len(text) > math.MaxUint16 {
+				return nil, ErrFieldTooLong
+			}
+			buf = binary.BigEndian.AppendUint16(buf, uint16(len(text)))
+			buf = append(buf, text...)
+		}
+		buf, err = appendSeriesValues(buf, series)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// copy meta bytes
+	buf = append(buf, metaBytes...)
+
+	// add checksum
+	buf = append(buf, make([]byte, checksumByteSize)...)
+	putChecksum(buf)
+
+	return buf, nil
+}
+
+// EncodeIndicatorSetLegacy writes the headerless layout read by releases
+// predating the versioned format. Series names, kinds and axes must be
+// shorter than seriesFieldByteSize, as those readers expect a terminating
+// zero byte.
+func EncodeIndicatorSetLegacy(ind *Indicator) ([]byte, error) {
+
+	// encode meta data
+	var metaBuf bytes.Buffer
+	err := gob.NewEncoder(&metaBuf).Encode(ind.Meta)
+	if err != nil {
+		return nil, err
+	}
+	metaBytes := metaBuf.Bytes()
+
 	// create main buffer
 	buf := make([]byte, 8+seriesByteSize*len(ind.Series)+len(metaBytes))
 
@@ -117,21 +237,18 @@ func EncodeIndicatorSet(ind *Indicator) ([]byte, error) {
 	binary.BigEndian.PutUint64(buf[0:], uint64(len(ind.Series)))
 
 	// add each series
-	index := 0
-	for key, series := range ind.Series {
+	for index, key := range ind.SeriesKeys() {
+		series := ind.Series[key]
+		if len(key) >= seriesFieldByteSize || len(series.Kind) >= seriesFieldByteSize || len(series.Axis) >= seriesFieldByteSize {
+			return nil, ErrFieldTooLong
+		}
+		if len(series.Values) > int(CandleSetSize) {
+			return nil, ErrFieldTooLong
+		}
 		copy(buf[8+index*seriesByteSize+0*seriesFieldByteSize:], key)
 		copy(buf[8+index*seriesByteSize+1*seriesFieldByteSize:], series.Kind)
 		copy(buf[8+index*seriesByteSize+2*seriesFieldByteSize:], series.Axis)
-		offset := 8 + index*seriesByteSize + 3*seriesFieldByteSize
-		for i, v := range series.Values {
-			binary.BigEndian.PutUint64(buf[offset+i*indicatorValueByteSize:], math.Float64bits(v.Value))
-			isMissing := uint8(0)
-			if v.Missing {
-				isMissing = 1
-			}
-			buf[offset+i*indicatorValueByteSize+8] = isMissing
-		}
-		index++
+		putIndicatorValues(buf[8+index*seriesByteSize+3*seriesFieldByteSize:], series.Values)
 	}
 
 	// copy meta bytes
@@ -140,44 +257,133 @@ func EncodeIndicatorSet(ind *Indicator) ([]byte, error) {
 	return buf, nil
 }
 
+func putIndicatorValues(buf []byte, values []IndicatorValue) {
+	for i, v := range values {
+		binary.BigEndian.PutUint64(buf[i*indicatorValueByteSize:], math.Float64bits(v.Value))
+		isMissing := uint8(0)
+		if v.Missing {
+			isMissing = 1
+		}
+		buf[i*indicatorValueByteSize+8] = isMissing
+	}
+}
+
+func readIndicatorValues(data []byte, n int) []IndicatorValue {
+	values := make([]IndicatorValue, n)
+	for i := range values {
+		values[i] = IndicatorValue{
+			Value:   math.Float64frombits(binary.BigEndian.Uint64(data[i*indicatorValueByteSize:])),
+			Missing: data[i*indicatorValueByteSize+8] == 1,
+		}
+	}
+	return values
+}
+
 func DecodeIndicatorSet(data []byte) (*Indicator, error) {
+	h, ok := readBlockHeader(data, indicatorMagic)
+	if !ok {
+		return decodeIndicatorSetV0(data)
+	}
+	if h.codec != CodecRaw {
+		return nil, ErrUnsupportedCodec
+	}
+	if h.version < formatVersion1 || h.version > formatVersion4 {
+		return nil, ErrUnsupportedVersion
+	}
+	payload, err := blockPayload(data, h)
+	if err != nil {
+		return nil, err
+	}
+	if h.version == formatVersion1 {
+		return decodeIndicatorSetV0(payload)
+	}
+	return decodeIndicatorSetVersioned(payload, h.version)
+}
 
-	numberOfSeries := int(binary.BigEndian.Uint64(data[0:]))
+func decodeIndicatorSetV0(data []byte) (*Indicator, error) {
+
+	numberOfSeries, err := readCount(data, seriesByteSize)
+	if err != nil {
+		return nil, err
+	}
 
 	seriesMap := make(map[string]*IndicatorSeries)
 
 	for index := 0; index < numberOfSeries; index++ {
 
-		series := &IndicatorSeries{
-			Values: make([]IndicatorValue, CandleSetSize),
-			Kind:   "",
-			Axis:   "",
-		}
+		series := &IndicatorSeries{}
 
 		// decode text fields
 		o := 8 + index*seriesByteSize
-		bytesKey := data[o+0*seriesFieldByteSize : o+1*seriesFieldByteSize]
-		key := string(bytesKey[:bytes.IndexByte(bytesKey, 0)])
-		bytesKind := data[o+1*seriesFieldByteSize : o+2*seriesFieldByteSize]
-		series.Kind = SeriesType(bytesKind[:bytes.IndexByte(bytesKind, 0)])
-		bytesAxis := data[o+2*seriesFieldByteSize : o+3*seriesFieldByteSize]
-		series.Axis = AxisType(bytesAxis[:bytes.IndexByte(bytesAxis, 0)])
+		key := fieldString(data[o+0*seriesFieldByteSize : o+1*seriesFieldByteSize])
+		series.Kind = SeriesType(fieldString(data[o+1*seriesFieldByteSize : o+2*seriesFieldByteSize]))
+		series.Axis = AxisType(fieldString(data[o+2*seriesFieldByteSize : o+3*seriesFieldByteSize]))
 
 		// decode values
-		o = 8 + index*seriesByteSize + 3*seriesFieldByteSize
-		for i := 0; i < int(CandleSetSize); i++ {
-			series.Values[i] = IndicatorValue{
-				Value:   math.Float64frombits(binary.BigEndian.Uint64(data[o+i*indicatorValueByteSize:])),
-				Missing: data[o+i*indicatorValueByteSize+8] == 1,
-			}
+		series.Values = readIndicatorValues(data[o+3*seriesFieldByteSize:], int(CandleSetSize))
+
+		seriesMap[key] = series
+	}
+
+	var meta IndicatorMeta
+	err = decodeMeta(data[8+seriesByteSize*numberOfSeries:], &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	ind := &Indicator{
+		Series: seriesMap,
+		Meta:   meta,
+	}
+
+	return ind, nil
+}
+
+// decodeIndicatorSetVersioned reads series with length prefixed text
+// fields. Since version 3 each series is preceded by its number of values,
+// before that every series holds CandleSetSize values. Version 4 adds the
+// series storage type and a missing bitmap.
+func decodeIndicatorSetVersioned(data []byte, version uint8) (*Indicator, error) {
+
+	minSeriesByteSize := 3*2 + 8
+	if version >= formatVersion4 {
+		minSeriesByteSize += 2
+	}
+	if version < formatVersion3 {
+		minSeriesByteSize = 3*2 + int(CandleSetSize)*indicatorValueByteSize
+	}
+	numberOfSeries, err := readCount(data, minSeriesByteSize)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesMap := make(map[string]*IndicatorSeries)
+
+	r := &byteReader{data: data, pos: 8}
+	for index := 0; index < numberOfSeries; index++ {
+
+		// decode text fields
+		key := r.string()
+		series := &IndicatorSeries{
+			Kind: SeriesType(r.string()),
+			Axis: AxisType(r.string()),
+		}
+
+		// decode values
+		if version >= formatVersion4 {
+			series.Values = readSeriesValues(r, series)
+		} else {
+			series.Values = readFloat64SeriesValues(r, version)
+		}
+		if r.err != nil {
+			return nil, r.err
 		}
 
 		seriesMap[key] = series
 	}
 
-	metaBytes := bytes.NewReader(data[8+seriesByteSize*numberOfSeries:])
 	var meta IndicatorMeta
-	err := gob.NewDecoder(metaBytes).Decode(&meta)
+	err = decodeMeta(data[r.pos:], &meta)
 	if err != nil {
 		return nil, err
 	}
@@ -189,3 +395,30 @@ func DecodeIndicatorSet(data []byte) (*Indicator, error) {
 
 	return ind, nil
 }
+
+// readFloat64SeriesValues reads the series values of versions 2 and 3.
+func readFloat64SeriesValues(r *byteReader, version uint8) []IndicatorValue {
+	numberOfValues := int(CandleSetSize)
+	if version >= formatVersion3 {
+		count := r.uint64()
+		if count > uint64(len(r.data)/indicatorValueByteSize) {
+			r.err = ErrTruncated
+			return nil
+		}
+		numberOfValues = int(count)
+	}
+	values := r.bytes(numberOfValues * indicatorValueByteSize)
+	if r.err != nil {
+		return nil
+	}
+	return readIndicatorValues(values, numberOfValues)
+}
+
+// fieldString reads a zero padded text field, which is not terminated
+// when the text fills the whole field.
+func fieldString(field []byte) string {
+	if n := bytes.IndexByte(field, 0); n >= 0 {
+		return string(field[:n])
+	}
+	return string(field)
+}
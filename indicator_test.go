@@ -5,6 +5,7 @@ import (
 	"encoding/gob"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"testing"
 )
@@ -135,3 +136,212 @@ func BenchmarkIndicatorDecodeGob(b *testing.B) {
 		}
 	}
 }
+
+func TestIndicatorBinaryTruncated(t *testing.T) {
+	data := randomIndicatorSet()
+	bin, err := EncodeIndicatorSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, n := range []int{0, 7, 8, 100, len(bin) / 2, len(bin) - 1} {
+		_, err = DecodeIndicatorSet(bin[:n])
+		if err == nil {
+			fmt.Printf("decoding %d of %d bytes did not fail\n", n, len(bin))
+			t.FailNow()
+		}
+	}
+}
+
+func TestIndicatorBinaryLongName(t *testing.T) {
+	data := randomIndicatorSet()
+	values := data.Series["series0"].Values
+	for _, name := range []string{"twenty_bytes_exactly", "bollinger_upper_band_2sd"} {
+		data.Series = map[string]*IndicatorSeries{
+			name: {Values: values, Kind: LineChart, Axis: PriceAxis},
+		}
+		bin, err := EncodeIndicatorSet(data)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		decoded, err := DecodeIndicatorSet(bin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if s, ok := decoded.Series[name]; !ok || s.Kind != LineChart || s.Axis != PriceAxis {
+			fmt.Printf("series name did not survive: %v\n", decoded.Series)
+			t.FailNow()
+		}
+		if _, err = EncodeIndicatorSetLegacy(data); err != ErrFieldTooLong {
+			fmt.Printf("expected field too long, got %v\n", err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestIndicatorBinaryLegacy(t *testing.T) {
+	data := randomIndicatorSet()
+	bin, err := EncodeIndicatorSetLegacy(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	decoded, err := DecodeIndicatorSet(bin)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for key, series := range data.Series {
+		for i := range series.Values {
+			if decoded.Series[key].Values[i] != series.Values[i] {
+				fmt.Printf("legacy indicator value %d did not match\n", i)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestIndicatorBinaryChecksum(t *testing.T) {
+	data := randomIndicatorSet()
+	bin, err := EncodeIndicatorSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = VerifyBlock(bin); err != nil {
+		log.Fatalln(err)
+	}
+	bin[len(bin)/2] ^= 0x80
+	if _, err = DecodeIndicatorSet(bin); err != ErrChecksumMismatch {
+		fmt.Printf("expected checksum mismatch, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func TestIndicatorBinaryDeterministic(t *testing.T) {
+	data := randomIndicatorSet()
+	first, err := EncodeIndicatorSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for i := 0; i < 20; i++ {
+		bin, err := EncodeIndicatorSet(data)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if !bytes.Equal(first, bin) {
+			fmt.Printf("encoding %d differs from the first\n", i)
+			t.FailNow()
+		}
+	}
+	decoded, err := DecodeIndicatorSet(first)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !data.Equal(decoded) {
+		fmt.Printf("decoded indicator is not equal\n")
+		t.FailNow()
+	}
+	decoded.Series["series2"].Values[10].Value++
+	if data.Equal(decoded) {
+		fmt.Printf("modified indicator is still equal\n")
+		t.FailNow()
+	}
+}
+
+func TestIndicatorBinaryLength(t *testing.T) {
+	data := randomIndicatorSet()
+	data.Series["series1"].Values = data.Series["series1"].Values[:1234]
+	data.Series["series2"].Values = nil
+	data.Series["series3"].Values = append(data.Series["series3"].Values, IndicatorValue{Missing: true})
+	bin, err := EncodeIndicatorSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	decoded, err := DecodeIndicatorSet(bin)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for key, series := range data.Series {
+		if len(decoded.Series[key].Values) != len(series.Values) {
+			fmt.Printf("series %s has %d values instead of %d\n", key, len(decoded.Series[key].Values), len(series.Values))
+			t.FailNow()
+		}
+	}
+	if !data.Equal(decoded) {
+		fmt.Printf("decoded indicator is not equal\n")
+		t.FailNow()
+	}
+}
+
+func TestIndicatorBinaryStorage(t *testing.T) {
+	data := randomIndicatorSet()
+	for key, series := range data.Series {
+		for i := range series.Values {
+			series.Values[i].Value *= 100
+			series.Values[i].Missing = i%10 == 3
+		}
+		switch key {
+		case "series1":
+			series.Storage = StorageFloat32
+		case "series2":
+			series.Storage = StorageFixed
+			series.Scale = 2
+		}
+	}
+	bin, err := EncodeIndicatorSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	decoded, err := DecodeIndicatorSet(bin)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	tolerance := map[string]float64{"series0": 0, "series1": 1e-4, "series2": 0.005, "series3": 0}
+	for key, series := range data.Series {
+		d := decoded.Series[key]
+		if d.Storage != series.Storage || d.Scale != series.Scale || len(d.Values) != len(series.Values) {
+			fmt.Printf("series %s storage did not match\n", key)
+			t.FailNow()
+		}
+		for i, v := range series.Values {
+			if d.Values[i].Missing != v.Missing || !v.Missing && math.Abs(d.Values[i].Value-v.Value) > tolerance[key] {
+				fmt.Printf("series %s value %d did not match: %v and %v\n", key, i, v, d.Values[i])
+				t.FailNow()
+			}
+		}
+	}
+
+	data.Series["series2"].Values[0] = IndicatorValue{Value: 1e20}
+	if _, err = EncodeIndicatorSet(data); err != ErrUnrepresentable {
+		fmt.Printf("expected unrepresentable, got %v\n", err)
+		t.FailNow()
+	}
+	data.Series["series2"].Values[0] = IndicatorValue{Missing: true}
+
+	// float32 keeps infinities but rejects finite values beyond its range
+	data.Series["series1"].Values[0] = IndicatorValue{Value: math.Inf(-1)}
+	if _, err = EncodeIndicatorSet(data); err != nil {
+		fmt.Printf("expected infinity to encode as float32, got %v\n", err)
+		t.FailNow()
+	}
+	data.Series["series1"].Values[0] = IndicatorValue{Value: 1e300}
+	if _, err = EncodeIndicatorSet(data); err != ErrUnrepresentable {
+		fmt.Printf("expected unrepresentable float32, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func BenchmarkIndicatorEncodeFixed(b *testing.B) {
+	ind := randomIndicatorSet()
+	for _, series := range ind.Series {
+		series.Storage = StorageFixed
+		series.Scale = 4
+	}
+	var bin []byte
+	var err error
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bin, err = EncodeIndicatorSet(ind)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	b.ReportMetric(float64(len(bin)), "bytes/block")
+}
@@ -0,0 +1,45 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/godoji/candlestick"
+)
+
+// ATR is the average true range, smoothed using Wilder's method. The true
+// range of the first candle is its high low range.
+func ATR(candles []candlestick.Candle, period int) *candlestick.Indicator {
+	ind := newIndicator("atr", period)
+	values := addSeries(ind, "atr", len(candles), candlestick.LineChart, candlestick.CustomAxis)
+	if period < 1 {
+		return ind
+	}
+	s := newATRState(period)
+	for i := range candles {
+		if candles[i].Missing {
+			continue
+		}
+		values[i].Value, values[i].Missing = s.update(&candles[i])
+	}
+	return ind
+}
+
+type atrState struct {
+	Started   bool
+	PrevClose float64
+	Average   wilderState
+}
+
+func newATRState(period int) *atrState {
+	return &atrState{Average: wilderState{Period: period}}
+}
+
+func (s *atrState) update(c *candlestick.Candle) (float64, bool) {
+	tr := c.High - c.Low
+	if s.Started {
+		tr = math.Max(tr, math.Max(math.Abs(c.High-s.PrevClose), math.Abs(c.Low-s.PrevClose)))
+	}
+	s.Started = true
+	s.PrevClose = c.Close
+	return s.Average.update(tr)
+}
@@ -0,0 +1,135 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/godoji/candlestick"
+)
+
+// SMA is the simple moving average of the close over period candles.
+func SMA(candles []candlestick.Candle, period int) *candlestick.Indicator {
+	ind := newIndicator("sma", period)
+	values := addSeries(ind, "sma", len(candles), candlestick.LineChart, candlestick.PriceAxis)
+	if period < 1 {
+		return ind
+	}
+	s := newSMAState(period)
+	for i := range candles {
+		if candles[i].Missing {
+			continue
+		}
+		values[i].Value, values[i].Missing = s.update(candles[i].Close)
+	}
+	return ind
+}
+
+// EMA is the exponential moving average of the close with a smoothing
+// factor of 2 / (period + 1), seeded with the SMA of the first period
+// candles.
+func EMA(candles []candlestick.Candle, period int) *candlestick.Indicator {
+	ind := newIndicator("ema", period)
+	values := addSeries(ind, "ema", len(candles), candlestick.LineChart, candlestick.PriceAxis)
+	if period < 1 {
+		return ind
+	}
+	s := newEMAState(period)
+	for i := range candles {
+		if candles[i].Missing {
+			continue
+		}
+		values[i].Value, values[i].Missing = s.update(candles[i].Close)
+	}
+	return ind
+}
+
+// smaState keeps a running sum over a ring buffer of the last values. A
+// NaN or infinite value poisons the running sum, so the sum is recomputed
+// from the window once such a value leaves it.
+type smaState struct {
+	Window []float64
+	Next   int
+	N      int
+	Sum    float64
+}
+
+func newSMAState(period int) *smaState {
+	return &smaState{Window: make([]float64, period)}
+}
+
+// update adds v and returns the average, which is missing until the
+// window is full.
+func (s *smaState) update(v float64) (float64, bool) {
+	resum := false
+	if s.N == len(s.Window) {
+		old := s.Window[s.Next]
+		s.Sum -= old
+		resum = math.IsNaN(old) || math.IsInf(old, 0)
+	} else {
+		s.N++
+	}
+	s.Window[s.Next] = v
+	if resum {
+		s.Sum = 0
+		for _, x := range s.Window {
+			s.Sum += x
+		}
+	} else {
+		s.Sum += v
+	}
+	s.Next = (s.Next + 1) % len(s.Window)
+	if s.N < len(s.Window) {
+		return 0, true
+	}
+	return s.Sum / float64(len(s.Window)), false
+}
+
+type emaState struct {
+	Period int
+	Alpha  float64
+	Seed   float64
+	N      int
+	Value  float64
+}
+
+func newEMAState(period int) *emaState {
+	return &emaState{
+		Period: period,
+		Alpha:  2 / float64(period+1),
+	}
+}
+
+func (s *emaState) update(v float64) (float64, bool) {
+	if s.N < s.Period {
+		s.N++
+		s.Seed += v
+		if s.N < s.Period {
+			return 0, true
+		}
+		s.Value = s.Seed / float64(s.Period)
+		return s.Value, false
+	}
+	s.Value += s.Alpha * (v - s.Value)
+	return s.Value, false
+}
+
+// wilderState is Wilder's smoothing, an EMA with a smoothing factor of
+// 1 / period seeded with the SMA of the first period values.
+type wilderState struct {
+	Period int
+	N      int
+	Value  float64
+}
+
+func (s *wilderState) update(v float64) (float64, bool) {
+	if s.N < s.Period {
+		s.N++
+		s.Value += v
+		if s.N < s.Period {
+			return 0, true
+		}
+		s.Value /= float64(s.Period)
+		return s.Value, false
+	}
+	s.Value = (s.Value*float64(s.Period-1) + v) / float64(s.Period)
+	return s.Value, false
+}
@@ -0,0 +1,95 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/godoji/candlestick"
+)
+
+// Bollinger are the Bollinger Bands of the close: the SMA over period
+// candles and bands the given number of population standard deviations
+// above and below it.
+func Bollinger(candles []candlestick.Candle, period int, deviations int) *candlestick.Indicator {
+	ind := newIndicator("bollinger", period, deviations)
+	middle := addSeries(ind, "middle", len(candles), candlestick.LineChart, candlestick.PriceAxis)
+	upper := addSeries(ind, "upper", len(candles), candlestick.LineChart, candlestick.PriceAxis)
+	lower := addSeries(ind, "lower", len(candles), candlestick.LineChart, candlestick.PriceAxis)
+	if period < 1 {
+		return ind
+	}
+	s := newBollingerState(period, deviations)
+	for i := range candles {
+		if candles[i].Missing {
+			continue
+		}
+		middle[i], upper[i], lower[i] = s.update(candles[i].Close)
+	}
+	return ind
+}
+
+// bollingerState keeps the mean and the sum of squared deviations of the
+// window up to date with Welford's method, replacing the oldest value once
+// the window is full. Both are rebuilt from the window each time it wraps
+// around, so that rounding errors do not accumulate, and once a NaN or
+// infinite value leaves it.
+type bollingerState struct {
+	Window     []float64
+	Next       int
+	N          int
+	Mean       float64
+	M2         float64
+	Deviations float64
+}
+
+func newBollingerState(period int, deviations int) *bollingerState {
+	return &bollingerState{
+		Window:     make([]float64, period),
+		Deviations: float64(deviations),
+	}
+}
+
+func (s *bollingerState) update(close float64) (middle, upper, lower candlestick.IndicatorValue) {
+	rebuild := false
+	if s.N < len(s.Window) {
+		s.N++
+		delta := close - s.Mean
+		s.Mean += delta / float64(s.N)
+		s.M2 += delta * (close - s.Mean)
+	} else {
+		old := s.Window[s.Next]
+		rebuild = math.IsNaN(old) || math.IsInf(old, 0)
+		mean := s.Mean + (close-old)/float64(s.N)
+		s.M2 += (close - old) * (close - mean + old - s.Mean)
+		s.Mean = mean
+	}
+	s.Window[s.Next] = close
+	s.Next = (s.Next + 1) % len(s.Window)
+	if s.N < len(s.Window) {
+		missingValue := candlestick.IndicatorValue{Missing: true}
+		return missingValue, missingValue, missingValue
+	}
+	if rebuild || s.Next == 0 {
+		s.rebuild()
+	}
+
+	// rounding can leave a tiny negative sum for constant windows
+	width := s.Deviations * math.Sqrt(math.Max(s.M2, 0)/float64(s.N))
+	middle.Value = s.Mean
+	upper.Value = s.Mean + width
+	lower.Value = s.Mean - width
+	return middle, upper, lower
+}
+
+// rebuild recomputes the mean and the sum of squared deviations of a full
+// window.
+func (s *bollingerState) rebuild() {
+	s.Mean = 0
+	for _, x := range s.Window {
+		s.Mean += x
+	}
+	s.Mean /= float64(len(s.Window))
+	s.M2 = 0
+	for _, x := range s.Window {
+		s.M2 += (x - s.Mean) * (x - s.Mean)
+	}
+}
@@ -0,0 +1,50 @@
+// Package indicators computes technical indicators over candles.
+//
+// Every indicator takes the candles in time order and returns an Indicator
+// holding one value per candle. Missing candles are skipped, so they do not
+// affect later values, and produce missing values. Values are also missing
+// until an indicator has seen enough candles to be defined, and all values
+// are missing for periods below 1.
+package indicators
+
+import (
+	"github.com/godoji/candlestick"
+)
+
+// ForBlock fills in the meta data of an indicator computed over the
+// candles of cs and returns it, as in
+//
+//	ind := indicators.ForBlock(indicators.RSI(cs.Candles, 14), cs)
+func ForBlock(ind *candlestick.Indicator, cs *candlestick.CandleSet) *candlestick.Indicator {
+	ind.Meta.Block = cs.Meta.Block
+	ind.Meta.Complete = cs.Meta.Complete
+	ind.Meta.LastUpdate = cs.Meta.LastUpdate
+	ind.Meta.Symbol = cs.Meta.Symbol
+	ind.Meta.Interval = cs.Meta.Interval
+	ind.Meta.BaseInterval = cs.Meta.Interval
+	return ind
+}
+
+func newIndicator(name string, parameters ...int) *candlestick.Indicator {
+	return &candlestick.Indicator{
+		Series: make(map[string]*candlestick.IndicatorSeries),
+		Meta: candlestick.IndicatorMeta{
+			Name:       name,
+			Parameters: parameters,
+		},
+	}
+}
+
+// addSeries adds a series of n values, all missing.
+func addSeries(ind *candlestick.Indicator, name string, n int, kind candlestick.SeriesType, axis candlestick.AxisType) []candlestick.IndicatorValue {
+	values := make([]candlestick.IndicatorValue, n)
+	for i := range values {
+		values[i].Missing = true
+	}
+	ind.Series[name] = &candlestick.IndicatorSeries{
+		Values: values,
+		Kind:   kind,
+		Axis:   axis,
+	}
+	return values
+}
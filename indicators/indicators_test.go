@@ -0,0 +1,350 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/godoji/candlestick"
+)
+
+func walkCandles(n int) []candlestick.Candle {
+	candles := make([]candlestick.Candle, n)
+	price := 100.0
+	for i := range candles {
+		candles[i].Time = int64(i) * candlestick.Interval1m
+		if i%97 == 13 {
+			candles[i].Missing = true
+			continue
+		}
+		open := price
+		price += float64(rand.Intn(21)-10) * 0.01
+		candles[i].Open = open
+		candles[i].High = math.Max(open, price) + float64(rand.Intn(3))*0.01
+		candles[i].Low = math.Min(open, price) - float64(rand.Intn(3))*0.01
+		candles[i].Close = price
+	}
+	return candles
+}
+
+// present returns the candles that are not missing and their indices.
+func present(candles []candlestick.Candle) ([]candlestick.Candle, []int) {
+	var result []candlestick.Candle
+	var indices []int
+	for i, c := range candles {
+		if !c.Missing {
+			result = append(result, c)
+			indices = append(indices, i)
+		}
+	}
+	return result, indices
+}
+
+// expectSeries compares a series against reference values computed over
+// the present candles, where NaN marks a missing value.
+func expectSeries(t *testing.T, ind *candlestick.Indicator, name string, candles []candlestick.Candle, expected []float64) {
+	series, ok := ind.Series[name]
+	if !ok || len(series.Values) != len(candles) {
+		fmt.Printf("%s: series %s not found or of wrong length\n", ind.Meta.Name, name)
+		t.FailNow()
+	}
+	_, indices := present(candles)
+	for i, c := range candles {
+		if c.Missing && !series.Values[i].Missing {
+			fmt.Printf("%s: %s value %d should be missing\n", ind.Meta.Name, name, i)
+			t.FailNow()
+		}
+	}
+	for j, i := range indices {
+		v := series.Values[i]
+		if math.IsNaN(expected[j]) != v.Missing || !v.Missing && math.Abs(v.Value-expected[j]) > 1e-9 {
+			fmt.Printf("%s: %s value %d is %v, expected %v\n", ind.Meta.Name, name, i, v, expected[j])
+			t.FailNow()
+		}
+	}
+}
+
+func referenceSMA(xs []float64, period int) []float64 {
+	result := make([]float64, len(xs))
+	for i := range xs {
+		result[i] = math.NaN()
+		if i >= period-1 && !math.IsNaN(xs[i-period+1]) {
+			var sum float64
+			for _, x := range xs[i-period+1 : i+1] {
+				sum += x
+			}
+			result[i] = sum / float64(period)
+		}
+	}
+	return result
+}
+
+func referenceEMA(xs []float64, period int, alpha float64) []float64 {
+	result := make([]float64, len(xs))
+	first := 0
+	for first < len(xs) && math.IsNaN(xs[first]) {
+		first++
+	}
+	for i := range xs {
+		result[i] = math.NaN()
+		switch {
+		case i == first+period-1:
+			result[i] = referenceSMA(xs[first:i+1], period)[period-1]
+		case i > first+period-1:
+			result[i] = result[i-1] + alpha*(xs[i]-result[i-1])
+		}
+	}
+	return result
+}
+
+func closes(candles []candlestick.Candle) []float64 {
+	xs := make([]float64, len(candles))
+	for i, c := range candles {
+		xs[i] = c.Close
+	}
+	return xs
+}
+
+func TestMovingAverages(t *testing.T) {
+	candles := walkCandles(1000)
+	cs, _ := present(candles)
+	xs := closes(cs)
+	ind := SMA(candles, 20)
+	if ind.Meta.Name != "sma" || ind.Meta.Parameters[0] != 20 || ind.Series["sma"].Axis != candlestick.PriceAxis {
+		fmt.Printf("unexpected sma meta\n")
+		t.FailNow()
+	}
+	expectSeries(t, ind, "sma", candles, referenceSMA(xs, 20))
+	expectSeries(t, EMA(candles, 20), "ema", candles, referenceEMA(xs, 20, 2.0/21))
+}
+
+func TestRSI(t *testing.T) {
+	candles := walkCandles(1000)
+	cs, _ := present(candles)
+	xs := closes(cs)
+	gains := []float64{math.NaN()}
+	losses := []float64{math.NaN()}
+	for i := 1; i < len(xs); i++ {
+		gains = append(gains, math.Max(xs[i]-xs[i-1], 0))
+		losses = append(losses, math.Max(xs[i-1]-xs[i], 0))
+	}
+	avgGain := referenceEMA(gains, 14, 1.0/14)
+	avgLoss := referenceEMA(losses, 14, 1.0/14)
+	expected := make([]float64, len(xs))
+	for i := range xs {
+		expected[i] = 100 - 100/(1+avgGain[i]/avgLoss[i])
+	}
+	expectSeries(t, RSI(candles, 14), "rsi", candles, expected)
+}
+
+func TestMACD(t *testing.T) {
+	candles := walkCandles(1000)
+	cs, _ := present(candles)
+	xs := closes(cs)
+	fast := referenceEMA(xs, 12, 2.0/13)
+	slow := referenceEMA(xs, 26, 2.0/27)
+	macd := make([]float64, len(xs))
+	for i := range xs {
+		macd[i] = fast[i] - slow[i]
+	}
+	signal := referenceEMA(macd, 9, 2.0/10)
+	histogram := make([]float64, len(xs))
+	for i := range xs {
+		histogram[i] = macd[i] - signal[i]
+	}
+	ind := MACD(candles, 12, 26, 9)
+	expectSeries(t, ind, "macd", candles, macd)
+	expectSeries(t, ind, "signal", candles, signal)
+	expectSeries(t, ind, "histogram", candles, histogram)
+	if ind.Series["histogram"].Kind != candlestick.BarChart {
+		fmt.Printf("histogram should be a bar chart\n")
+		t.FailNow()
+	}
+}
+
+func TestNonFiniteClose(t *testing.T) {
+	for _, bad := range []float64{math.NaN(), math.Inf(1)} {
+		candles := walkCandles(200)
+		candles[10].Close = bad
+		cs, indices := present(candles)
+		xs := closes(cs)
+		expected := referenceSMA(xs, 20)
+		ind := SMA(candles, 20)
+		for j, i := range indices {
+			// once the bad close has left the window
+			if i < 40 {
+				continue
+			}
+			v := ind.Series["sma"].Values[i]
+			if v.Missing || !(math.Abs(v.Value-expected[j]) <= 1e-9) {
+				fmt.Printf("sma value %d is %v after a %v close, expected %v\n", i, v, bad, expected[j])
+				t.FailNow()
+			}
+		}
+
+		ind = Bollinger(candles, 20, 2)
+		for j, i := range indices {
+			if i < 40 {
+				continue
+			}
+			var variance float64
+			for _, x := range xs[j-19 : j+1] {
+				variance += (x - expected[j]) * (x - expected[j])
+			}
+			width := 2 * math.Sqrt(variance/20)
+			middle := ind.Series["middle"].Values[i]
+			upper := ind.Series["upper"].Values[i]
+			if middle.Missing || !(math.Abs(middle.Value-expected[j]) <= 1e-9) ||
+				upper.Missing || !(math.Abs(upper.Value-expected[j]-width) <= 1e-9) {
+				fmt.Printf("bollinger values %d are %v and %v after a %v close, expected %v and %v\n", i, middle, upper, bad, expected[j], expected[j]+width)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	candles := walkCandles(1000)
+	cs, _ := present(candles)
+	xs := closes(cs)
+	middle := referenceSMA(xs, 20)
+	upper := make([]float64, len(xs))
+	lower := make([]float64, len(xs))
+	for i := range xs {
+		upper[i], lower[i] = math.NaN(), math.NaN()
+		if i >= 19 {
+			var variance float64
+			for _, x := range xs[i-19 : i+1] {
+				variance += (x - middle[i]) * (x - middle[i])
+			}
+			width := 2 * math.Sqrt(variance/20)
+			upper[i] = middle[i] + width
+			lower[i] = middle[i] - width
+		}
+	}
+	ind := Bollinger(candles, 20, 2)
+	expectSeries(t, ind, "middle", candles, middle)
+	expectSeries(t, ind, "upper", candles, upper)
+	expectSeries(t, ind, "lower", candles, lower)
+}
+
+func TestATR(t *testing.T) {
+	candles := walkCandles(1000)
+	cs, _ := present(candles)
+	tr := make([]float64, len(cs))
+	for i, c := range cs {
+		tr[i] = c.High - c.Low
+		if i > 0 {
+			tr[i] = math.Max(tr[i], math.Max(math.Abs(c.High-cs[i-1].Close), math.Abs(c.Low-cs[i-1].Close)))
+		}
+	}
+	expectSeries(t, ATR(candles, 14), "atr", candles, referenceEMA(tr, 14, 1.0/14))
+}
+
+func TestStochastic(t *testing.T) {
+	candles := walkCandles(1000)
+	cs, _ := present(candles)
+	raw := make([]float64, len(cs))
+	for i, c := range cs {
+		raw[i] = math.NaN()
+		if i >= 13 {
+			high, low := math.Inf(-1), math.Inf(1)
+			for _, p := range cs[i-13 : i+1] {
+				high = math.Max(high, p.High)
+				low = math.Min(low, p.Low)
+			}
+			raw[i] = 100 * (c.Close - low) / (high - low)
+		}
+	}
+	k := referenceSMA(raw, 3)
+	d := referenceSMA(k, 3)
+	ind := Stochastic(candles, 14, 3, 3)
+	expectSeries(t, ind, "k", candles, k)
+	expectSeries(t, ind, "d", candles, d)
+}
+
+func TestInvalidPeriod(t *testing.T) {
+	candles := walkCandles(100)
+	for _, period := range []int{0, -1} {
+		for _, ind := range []*candlestick.Indicator{
+			SMA(candles, period),
+			EMA(candles, period),
+			RSI(candles, period),
+			MACD(candles, period, 26, 9),
+			Bollinger(candles, period, 2),
+			ATR(candles, period),
+			Stochastic(candles, 14, period, 3),
+		} {
+			for name, series := range ind.Series {
+				for i, v := range series.Values {
+					if !v.Missing {
+						fmt.Printf("%s: %s value %d should be missing for period %d\n", ind.Meta.Name, name, i, period)
+						t.FailNow()
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestForBlock(t *testing.T) {
+	cs := &candlestick.CandleSet{
+		Candles: walkCandles(100),
+		Meta: candlestick.DataSetMeta{
+			Block:    7,
+			Complete: true,
+			Symbol:   "AAPLUSD",
+			Interval: candlestick.Interval1m,
+		},
+	}
+	ind := ForBlock(RSI(cs.Candles, 14), cs)
+	if ind.Meta.Block != 7 || !ind.Meta.Complete || ind.Meta.Symbol != "AAPLUSD" || ind.Meta.Interval != candlestick.Interval1m ||
+		ind.Meta.Name != "rsi" {
+		fmt.Printf("unexpected meta %v\n", ind.Meta)
+		t.FailNow()
+	}
+}
+
+func TestExtremeState(t *testing.T) {
+	const period = 7
+	highs := newExtremeState(period, true)
+	lows := newExtremeState(period, false)
+	var values []float64
+	for i := 0; i < 1000; i++ {
+		// few distinct values, so that ties and runs are common
+		v := float64(rand.Intn(5))
+		if i > 500 && i < 600 {
+			v = float64(i)
+		}
+		values = append(values, v)
+		highs.update(v)
+		lows.update(v)
+		if i < period-1 {
+			continue
+		}
+		high, low := math.Inf(-1), math.Inf(1)
+		for _, x := range values[i-period+1:] {
+			high = math.Max(high, x)
+			low = math.Min(low, x)
+		}
+		if !highs.full() || highs.value() != high || lows.value() != low {
+			fmt.Printf("window %d: got %v and %v, expected %v and %v\n", i, highs.value(), lows.value(), high, low)
+			t.FailNow()
+		}
+	}
+}
+
+func TestBollingerConstant(t *testing.T) {
+	candles := make([]candlestick.Candle, 100)
+	for i := range candles {
+		candles[i] = candlestick.Candle{Open: 0.1, High: 0.1, Low: 0.1, Close: 0.1}
+	}
+	ind := Bollinger(candles, 20, 2)
+	for _, key := range []string{"middle", "upper", "lower"} {
+		if v := ind.Series[key].Values[99]; v.Missing || math.Abs(v.Value-0.1) > 1e-12 {
+			fmt.Printf("unexpected %s value %v of constant candles\n", key, v)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package indicators
+
+import (
+	"errors"
+	"math"
+
+	"github.com/godoji/candlestick"
+)
+
+// convergenceEpsilon is the weight below which the state an exponentially
+// smoothed indicator started from is considered forgotten.
+const convergenceEpsilon = 1e-12
+
+// convergence returns the number of updates after which the starting value
+// of an exponential smoothing with factor alpha weighs less than
+// convergenceEpsilon. Recursive indicators depend on all preceding candles,
+// so their lookback is where this difference becomes negligible.
+func convergence(alpha float64) int {
+	if alpha >= 1 {
+		return 0
+	}
+	return int(math.Ceil(math.Log(convergenceEpsilon) / math.Log(1-alpha)))
+}
+
+// Lookback returns the lookback of the indicator named by meta.
+func (r *Registry) Lookback(meta candlestick.IndicatorMeta) (int, error) {
+	d, ok := r.Lookup(meta.Name)
+	if !ok {
+		return 0, ErrUnknownIndicator
+	}
+	parameters, err := d.resolve(meta.Parameters)
+	if err != nil {
+		return 0, err
+	}
+	if d.Lookback == nil {
+		return 0, nil
+	}
+	return d.Lookback(parameters), nil
+}
+
+// ComputeBlock computes the indicator named by meta for the candle block
+// meta.Block of symbol at meta.Interval. To give the same values as
+// computing over the full history, the candles of preceding blocks are
+// prepended until they hold as many present candles as the indicator's
+// lookback, or until the block holding historyStart, the time stamp the
+// history of symbol starts at, is reached. Preceding blocks that do not
+// exist are taken as all missing, as they are when computing over the full
+// history. For recursive indicators the values match up to the
+// precision given by convergenceEpsilon, relative to the scale of the
+// candles.
+func (r *Registry) ComputeBlock(source candlestick.BlockSource, symbol candlestick.AssetIdentifier, meta candlestick.IndicatorMeta, historyStart int64) (*candlestick.Indicator, error) {
+	lookback, err := r.Lookback(meta)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := source.GetCandleSet(symbol, meta.Interval, meta.Block)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Meta.Block != meta.Block || cs.Meta.Interval != meta.Interval {
+		return nil, candlestick.ErrMetaMismatch
+	}
+
+	// collect preceding blocks, newest first
+	var previous [][]candlestick.Candle
+	total := 0
+	first := candlestick.UnixToBlock(historyStart, meta.Interval)
+	for block, found := meta.Block-1, 0; found < lookback && block >= first; block-- {
+		prev, err := source.GetCandleSet(symbol, meta.Interval, block)
+		if errors.Is(err, candlestick.ErrBlockNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if prev.Meta.Block != block || prev.Meta.Interval != meta.Interval {
+			return nil, candlestick.ErrMetaMismatch
+		}
+
+		// preceding blocks are padded to full length, so that gaps
+		// in incomplete blocks show up as missing candles
+		candles := make([]candlestick.Candle, candlestick.CandleSetSize)
+		copy(candles, prev.Candles)
+		for i := len(prev.Candles); i < len(candles); i++ {
+			candles[i] = candlestick.Candle{Time: prev.TimeStampAtIndex(int64(i)), Missing: true}
+		}
+		for i := range prev.Candles {
+			if !prev.Candles[i].Missing {
+				found++
+			}
+		}
+		previous = append(previous, candles)
+		total += len(candles)
+	}
+
+	// compute over the joined candles and cut off the preceding ones
+	candles := make([]candlestick.Candle, 0, total+len(cs.Candles))
+	for i := len(previous) - 1; i >= 0; i-- {
+		candles = append(candles, previous[i]...)
+	}
+	candles = append(candles, cs.Candles...)
+	m := meta
+	m.Complete = cs.Meta.Complete
+	m.LastUpdate = cs.Meta.LastUpdate
+	m.Symbol = cs.Meta.Symbol
+	m.BaseInterval = meta.Interval
+	ind, err := r.Compute(m, candles)
+	if err != nil {
+		return nil, err
+	}
+	for _, series := range ind.Series {
+		values := make([]candlestick.IndicatorValue, len(series.Values)-total)
+		copy(values, series.Values[total:])
+		series.Values = values
+	}
+	return ind, nil
+}
+
+// ComputeBlock computes an indicator registered with DefaultRegistry for
+// a single block, see Registry.ComputeBlock.
+func ComputeBlock(source candlestick.BlockSource, symbol candlestick.AssetIdentifier, meta candlestick.IndicatorMeta, historyStart int64) (*candlestick.Indicator, error) {
+	return DefaultRegistry.ComputeBlock(source, symbol, meta, historyStart)
+}
@@ -0,0 +1,164 @@
+package indicators
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"testing"
+
+	"github.com/godoji/candlestick"
+)
+
+func TestComputeBlock(t *testing.T) {
+
+	// continuous history of four blocks, the last one incomplete
+	history := walkCandles(3*int(candlestick.CandleSetSize) + 1000)
+	blocks := map[int64]*candlestick.CandleSet{}
+	for b := int64(0); b < 4; b++ {
+		end := (b + 1) * candlestick.CandleSetSize
+		if end > int64(len(history)) {
+			end = int64(len(history))
+		}
+		blocks[b+10] = &candlestick.CandleSet{
+			Candles: history[b*candlestick.CandleSetSize : end],
+			Meta: candlestick.DataSetMeta{
+				Block:    b + 10,
+				Complete: end-b*candlestick.CandleSetSize == candlestick.CandleSetSize,
+				Symbol:   "AAPLUSD",
+				Interval: candlestick.Interval1m,
+			},
+		}
+	}
+	fetches := 0
+	source := candlestick.BlockSourceFunc(func(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+		fetches++
+		if cs, ok := blocks[block]; ok {
+			return cs, nil
+		}
+		return nil, candlestick.ErrBlockNotFound
+	})
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+
+	for _, meta := range []candlestick.IndicatorMeta{
+		{Name: "sma", Parameters: []int{200}},
+		{Name: "ema", Parameters: []int{200}},
+		{Name: "rsi", Parameters: []int{14}},
+		{Name: "macd"},
+		{Name: "bollinger"},
+		{Name: "atr"},
+		{Name: "stochastic"},
+	} {
+		full, err := Compute(meta, history)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for b := int64(10); b < 14; b++ {
+			meta.Block = b
+			meta.Interval = candlestick.Interval1m
+			fetches = 0
+			ind, err := ComputeBlock(source, symbol, meta, candlestick.BlockToUnix(10, candlestick.Interval1m))
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			// all lookbacks fit into the preceding block, and the first
+			// block has none
+			expectedFetches := 2
+			if b == 10 {
+				expectedFetches = 1
+			}
+			if ind.Meta.Block != b || ind.Meta.Complete != blocks[b].Meta.Complete || ind.Meta.UID != full.Meta.UID || fetches != expectedFetches {
+				fmt.Printf("%s block %d: unexpected meta %v after %d fetches\n", meta.Name, b, ind.Meta, fetches)
+				t.FailNow()
+			}
+			offset := (b - 10) * candlestick.CandleSetSize
+			for key, series := range ind.Series {
+				if len(series.Values) != len(blocks[b].Candles) {
+					fmt.Printf("%s block %d: series %s has %d values\n", meta.Name, b, key, len(series.Values))
+					t.FailNow()
+				}
+				for i, v := range series.Values {
+					expected := full.Series[key].Values[offset+int64(i)]
+					if v.Missing != expected.Missing || math.Abs(v.Value-expected.Value) > 1e-9 {
+						fmt.Printf("%s block %d: %s value %d is %v, expected %v\n", meta.Name, b, key, i, v, expected)
+						t.FailNow()
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestComputeBlockGap(t *testing.T) {
+
+	// history of four blocks, the second one absent
+	history := walkCandles(4 * int(candlestick.CandleSetSize))
+	blocks := map[int64]*candlestick.CandleSet{}
+	for b := int64(0); b < 4; b++ {
+		candles := history[b*candlestick.CandleSetSize : (b+1)*candlestick.CandleSetSize]
+		if b == 1 {
+			for i := range candles {
+				candles[i].Missing = true
+			}
+			continue
+		}
+		blocks[b] = &candlestick.CandleSet{
+			Candles: candles,
+			Meta: candlestick.DataSetMeta{
+				Block:    b,
+				Complete: true,
+				Symbol:   "AAPLUSD",
+				Interval: candlestick.Interval1m,
+			},
+		}
+	}
+	var fetched []int64
+	source := candlestick.BlockSourceFunc(func(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+		fetched = append(fetched, block)
+		if cs, ok := blocks[block]; ok {
+			return cs, nil
+		}
+		return nil, candlestick.ErrBlockNotFound
+	})
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+
+	for _, meta := range []candlestick.IndicatorMeta{
+		{Name: "ema", Parameters: []int{200}},
+		{Name: "rsi"},
+		{Name: "stochastic"},
+	} {
+		full, err := Compute(meta, history)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		meta.Block = 2
+		meta.Interval = candlestick.Interval1m
+		fetched = nil
+		ind, err := ComputeBlock(source, symbol, meta, 0)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		// the walk back passes the absent block, and stops at the start
+		if meta.Name == "ema" && (len(fetched) != 3 || fetched[2] != 0) {
+			fmt.Printf("%s: unexpected fetches %v\n", meta.Name, fetched)
+			t.FailNow()
+		}
+		for key, series := range ind.Series {
+			for i, v := range series.Values {
+				expected := full.Series[key].Values[2*candlestick.CandleSetSize+int64(i)]
+				if v.Missing != expected.Missing || math.Abs(v.Value-expected.Value) > 1e-9 {
+					fmt.Printf("%s: %s value %d is %v, expected %v\n", meta.Name, key, i, v, expected)
+					t.FailNow()
+				}
+			}
+		}
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	if convergence(1) != 0 || convergence(0.5) != 40 {
+		fmt.Printf("unexpected convergence %d, %d\n", convergence(1), convergence(0.5))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,59 @@
+package indicators
+
+import (
+	"github.com/godoji/candlestick"
+)
+
+// MACD is the difference between a fast and a slow EMA of the close, with
+// a signal line that is an EMA of the MACD and a histogram of the
+// difference between both.
+func MACD(candles []candlestick.Candle, fast int, slow int, signal int) *candlestick.Indicator {
+	ind := newIndicator("macd", fast, slow, signal)
+	macd := addSeries(ind, "macd", len(candles), candlestick.LineChart, candlestick.CustomAxis)
+	signals := addSeries(ind, "signal", len(candles), candlestick.LineChart, candlestick.CustomAxis)
+	histogram := addSeries(ind, "histogram", len(candles), candlestick.BarChart, candlestick.CustomAxis)
+	if fast < 1 || slow < 1 || signal < 1 {
+		return ind
+	}
+	s := newMACDState(fast, slow, signal)
+	for i := range candles {
+		if candles[i].Missing {
+			continue
+		}
+		macd[i], signals[i], histogram[i] = s.update(candles[i].Close)
+	}
+	return ind
+}
+
+type macdState struct {
+	Fast   *emaState
+	Slow   *emaState
+	Signal *emaState
+}
+
+func newMACDState(fast int, slow int, signal int) *macdState {
+	return &macdState{
+		Fast:   newEMAState(fast),
+		Slow:   newEMAState(slow),
+		Signal: newEMAState(signal),
+	}
+}
+
+// update returns the MACD, signal and histogram values.
+func (s *macdState) update(close float64) (macd, signal, histogram candlestick.IndicatorValue) {
+	fast, fastMissing := s.Fast.update(close)
+	slow, slowMissing := s.Slow.update(close)
+	signal.Missing = true
+	histogram.Missing = true
+	if fastMissing || slowMissing {
+		macd.Missing = true
+		return
+	}
+	macd.Value = fast - slow
+	signal.Value, signal.Missing = s.Signal.update(macd.Value)
+	if !signal.Missing {
+		histogram.Value = macd.Value - signal.Value
+		histogram.Missing = false
+	}
+	return macd, signal, histogram
+}
@@ -0,0 +1,287 @@
+package indicators
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/godoji/candlestick"
+)
+
+var ErrUnknownIndicator = errors.New("indicators: unknown indicator")
+var ErrInvalidParameters = errors.New("indicators: invalid parameters")
+var ErrInvalidDefinition = errors.New("indicators: invalid definition")
+
+// Parameter describes an integer parameter of an indicator and its
+// inclusive bounds.
+type Parameter struct {
+	Name    string `json:"name"`
+	Default int    `json:"default"`
+	Min     int    `json:"min"`
+	Max     int    `json:"max"`
+}
+
+// Output describes a series an indicator produces.
+type Output struct {
+	Name string                 `json:"name"`
+	Kind candlestick.SeriesType `json:"kind"`
+	Axis candlestick.AxisType   `json:"axis"`
+}
+
+// Definition describes how to compute an indicator. Compute is called with
+// one value for every parameter, within its bounds. Lookback returns the
+// number of present candles preceding a candle that its value depends on,
+// see ComputeBlock; it may be nil for indicators without lookback.
+// NewState creates the incremental state used by Stream; it may be nil for
+// indicators that cannot be streamed.
+type Definition struct {
+	Name       string
+	Parameters []Parameter
+	Outputs    []Output
+	Compute    func(candles []candlestick.Candle, parameters []int) *candlestick.Indicator
+	Lookback   func(parameters []int) int
+	NewState   func(parameters []int) State
+}
+
+// Registry maps indicator names to their definitions, so that indicators
+// can be recomputed from the name and parameters in their meta data.
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]*Definition
+}
+
+func NewRegistry() *Registry {
+	return &Registry{definitions: make(map[string]*Definition)}
+}
+
+// Register adds a definition. Names must be unique, and the defaults must
+// lie within the bounds of their parameters.
+func (r *Registry) Register(d Definition) error {
+	if d.Name == "" || d.Compute == nil {
+		return ErrInvalidDefinition
+	}
+	for _, p := range d.Parameters {
+		if p.Min > p.Max || p.Default < p.Min || p.Default > p.Max {
+			return ErrInvalidDefinition
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[d.Name]; ok {
+		return ErrInvalidDefinition
+	}
+	d.Parameters = append([]Parameter(nil), d.Parameters...)
+	d.Outputs = append([]Output(nil), d.Outputs...)
+	r.definitions[d.Name] = &d
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (*Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.definitions[name]
+	return d, ok
+}
+
+// Names returns the names of all registered indicators in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.definitions))
+	for name := range r.definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compute computes the indicator named by meta over candles. Parameters
+// left out at the end of meta.Parameters take their defaults. The result
+// carries meta with the full parameters and a UID derived from them.
+func (r *Registry) Compute(meta candlestick.IndicatorMeta, candles []candlestick.Candle) (*candlestick.Indicator, error) {
+	d, ok := r.Lookup(meta.Name)
+	if !ok {
+		return nil, ErrUnknownIndicator
+	}
+	parameters, err := d.resolve(meta.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	ind := d.Compute(candles, parameters)
+	ind.Meta = meta
+	ind.Meta.Parameters = parameters
+	ind.Meta.UID = UID(meta.Name, parameters)
+	return ind, nil
+}
+
+// resolve fills in defaults and checks parameters against their bounds.
+func (d *Definition) resolve(parameters []int) ([]int, error) {
+	if len(parameters) > len(d.Parameters) {
+		return nil, ErrInvalidParameters
+	}
+	resolved := make([]int, len(d.Parameters))
+	for i, p := range d.Parameters {
+		resolved[i] = p.Default
+		if i < len(parameters) {
+			resolved[i] = parameters[i]
+		}
+		if resolved[i] < p.Min || resolved[i] > p.Max {
+			return nil, ErrInvalidParameters
+		}
+	}
+	return resolved, nil
+}
+
+// UID derives the unique identifier of an indicator from its name and
+// parameters, such as "macd_12_26_9".
+func UID(name string, parameters []int) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, p := range parameters {
+		sb.WriteByte('_')
+		sb.WriteString(strconv.Itoa(p))
+	}
+	return sb.String()
+}
+
+// DefaultRegistry holds the indicators of this package.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a definition to DefaultRegistry.
+func Register(d Definition) error {
+	return DefaultRegistry.Register(d)
+}
+
+// Compute computes an indicator registered with DefaultRegistry.
+func Compute(meta candlestick.IndicatorMeta, candles []candlestick.Candle) (*candlestick.Indicator, error) {
+	return DefaultRegistry.Compute(meta, candles)
+}
+
+func period(name string, value int) Parameter {
+	return Parameter{Name: name, Default: value, Min: 1, Max: int(candlestick.CandleSetSize)}
+}
+
+func init() {
+	line := func(name string, axis candlestick.AxisType) Output {
+		return Output{Name: name, Kind: candlestick.LineChart, Axis: axis}
+	}
+	definitions := []Definition{
+		{
+			Name:       "sma",
+			Parameters: []Parameter{period("period", 20)},
+			Outputs:    []Output{line("sma", candlestick.PriceAxis)},
+			Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+				return SMA(candles, p[0])
+			},
+			Lookback: func(p []int) int {
+				return p[0] - 1
+			},
+			NewState: func(p []int) State {
+				return &smaStream{newSMAState(p[0])}
+			},
+		},
+		{
+			Name:       "ema",
+			Parameters: []Parameter{period("period", 20)},
+			Outputs:    []Output{line("ema", candlestick.PriceAxis)},
+			Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+				return EMA(candles, p[0])
+			},
+			Lookback: func(p []int) int {
+				return p[0] - 1 + convergence(2/float64(p[0]+1))
+			},
+			NewState: func(p []int) State {
+				return &emaStream{newEMAState(p[0])}
+			},
+		},
+		{
+			Name:       "rsi",
+			Parameters: []Parameter{period("period", 14)},
+			Outputs:    []Output{line("rsi", candlestick.CustomAxis)},
+			Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+				return RSI(candles, p[0])
+			},
+			Lookback: func(p []int) int {
+				return p[0] + convergence(1/float64(p[0]))
+			},
+			NewState: func(p []int) State {
+				return &rsiStream{newRSIState(p[0])}
+			},
+		},
+		{
+			Name:       "macd",
+			Parameters: []Parameter{period("fast", 12), period("slow", 26), period("signal", 9)},
+			Outputs: []Output{
+				line("macd", candlestick.CustomAxis),
+				line("signal", candlestick.CustomAxis),
+				{Name: "histogram", Kind: candlestick.BarChart, Axis: candlestick.CustomAxis},
+			},
+			Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+				return MACD(candles, p[0], p[1], p[2])
+			},
+			Lookback: func(p []int) int {
+				slow := p[0]
+				if p[1] > slow {
+					slow = p[1]
+				}
+				return slow - 1 + convergence(2/float64(slow+1)) + p[2] - 1 + convergence(2/float64(p[2]+1))
+			},
+			NewState: func(p []int) State {
+				return &macdStream{newMACDState(p[0], p[1], p[2])}
+			},
+		},
+		{
+			Name:       "bollinger",
+			Parameters: []Parameter{period("period", 20), {Name: "deviations", Default: 2, Min: 0, Max: 10}},
+			Outputs: []Output{
+				line("middle", candlestick.PriceAxis),
+				line("upper", candlestick.PriceAxis),
+				line("lower", candlestick.PriceAxis),
+			},
+			Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+				return Bollinger(candles, p[0], p[1])
+			},
+			Lookback: func(p []int) int {
+				return p[0] - 1
+			},
+			NewState: func(p []int) State {
+				return &bollingerStream{newBollingerState(p[0], p[1])}
+			},
+		},
+		{
+			Name:       "atr",
+			Parameters: []Parameter{period("period", 14)},
+			Outputs:    []Output{line("atr", candlestick.CustomAxis)},
+			Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+				return ATR(candles, p[0])
+			},
+			Lookback: func(p []int) int {
+				return p[0] + convergence(1/float64(p[0]))
+			},
+			NewState: func(p []int) State {
+				return &atrStream{newATRState(p[0])}
+			},
+		},
+		{
+			Name:       "stochastic",
+			Parameters: []Parameter{period("period", 14), period("smoothK", 3), period("smoothD", 3)},
+			Outputs:    []Output{line("k", candlestick.CustomAxis), line("d", candlestick.CustomAxis)},
+			Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+				return Stochastic(candles, p[0], p[1], p[2])
+			},
+			Lookback: func(p []int) int {
+				return p[0] - 1 + p[1] - 1 + p[2] - 1
+			},
+			NewState: func(p []int) State {
+				return &stochasticStream{newStochasticState(p[0], p[1], p[2])}
+			},
+		},
+	}
+	for _, d := range definitions {
+		if err := Register(d); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package indicators
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/godoji/candlestick"
+)
+
+func TestRegistryCompute(t *testing.T) {
+	candles := walkCandles(500)
+	meta := candlestick.IndicatorMeta{
+		Block:    3,
+		Symbol:   "AAPLUSD",
+		Interval: candlestick.Interval1m,
+		Name:     "macd",
+		Parameters: []int{
+			8,
+		},
+	}
+	ind, err := Compute(meta, candles)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if ind.Meta.UID != "macd_8_26_9" || ind.Meta.Block != 3 || len(ind.Meta.Parameters) != 3 || ind.Meta.Parameters[2] != 9 {
+		fmt.Printf("unexpected meta %v\n", ind.Meta)
+		t.FailNow()
+	}
+	expected := MACD(candles, 8, 26, 9)
+	for key, series := range expected.Series {
+		if !series.Equal(ind.Series[key]) {
+			fmt.Printf("series %s did not match\n", key)
+			t.FailNow()
+		}
+	}
+
+	meta.Parameters = []int{8, 0}
+	if _, err = Compute(meta, candles); err != ErrInvalidParameters {
+		fmt.Printf("expected invalid parameters, got %v\n", err)
+		t.FailNow()
+	}
+	meta.Parameters = []int{8, 26, 9, 1}
+	if _, err = Compute(meta, candles); err != ErrInvalidParameters {
+		fmt.Printf("expected invalid parameters, got %v\n", err)
+		t.FailNow()
+	}
+	meta.Name = "unknown"
+	if _, err = Compute(meta, candles); err != ErrUnknownIndicator {
+		fmt.Printf("expected unknown indicator, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func TestRegistryOutputs(t *testing.T) {
+	candles := walkCandles(100)
+	for _, name := range DefaultRegistry.Names() {
+		d, _ := DefaultRegistry.Lookup(name)
+		ind, err := Compute(candlestick.IndicatorMeta{Name: name}, candles)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if ind.Meta.UID != UID(name, ind.Meta.Parameters) || len(ind.Series) != len(d.Outputs) {
+			fmt.Printf("%s: unexpected result\n", name)
+			t.FailNow()
+		}
+		for _, o := range d.Outputs {
+			series, ok := ind.Series[o.Name]
+			if !ok || series.Kind != o.Kind || series.Axis != o.Axis {
+				fmt.Printf("%s: output %s does not match its descriptor\n", name, o.Name)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	d := Definition{
+		Name:       "close",
+		Parameters: []Parameter{{Name: "offset", Default: 0, Min: 0, Max: 10}},
+		Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+			return SMA(candles, 1)
+		},
+	}
+	if err := r.Register(d); err != nil {
+		log.Fatalln(err)
+	}
+	if err := r.Register(d); err != ErrInvalidDefinition {
+		fmt.Printf("expected duplicate definition to fail, got %v\n", err)
+		t.FailNow()
+	}
+	d.Name = "bounds"
+	d.Parameters[0].Default = 11
+	if err := r.Register(d); err != ErrInvalidDefinition {
+		fmt.Printf("expected out of bounds default to fail, got %v\n", err)
+		t.FailNow()
+	}
+	if names := r.Names(); len(names) != 1 || names[0] != "close" {
+		fmt.Printf("unexpected names %v\n", names)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,66 @@
+package indicators
+
+import (
+	"github.com/godoji/candlestick"
+)
+
+// RSI is the relative strength index of the close, with gains and losses
+// smoothed using Wilder's method. The first value is available once
+// period changes, so period + 1 candles, have been seen.
+func RSI(candles []candlestick.Candle, period int) *candlestick.Indicator {
+	ind := newIndicator("rsi", period)
+	values := addSeries(ind, "rsi", len(candles), candlestick.LineChart, candlestick.CustomAxis)
+	if period < 1 {
+		return ind
+	}
+	s := newRSIState(period)
+	for i := range candles {
+		if candles[i].Missing {
+			continue
+		}
+		values[i].Value, values[i].Missing = s.update(candles[i].Close)
+	}
+	return ind
+}
+
+type rsiState struct {
+	Started bool
+	Prev    float64
+	Gain    wilderState
+	Loss    wilderState
+}
+
+func newRSIState(period int) *rsiState {
+	return &rsiState{
+		Gain: wilderState{Period: period},
+		Loss: wilderState{Period: period},
+	}
+}
+
+func (s *rsiState) update(close float64) (float64, bool) {
+	if !s.Started {
+		s.Started = true
+		s.Prev = close
+		return 0, true
+	}
+	change := close - s.Prev
+	s.Prev = close
+	var gain, loss float64
+	if change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+	avgGain, missing := s.Gain.update(gain)
+	avgLoss, _ := s.Loss.update(loss)
+	if missing {
+		return 0, true
+	}
+	if avgLoss == 0 {
+		if avgGain == 0 {
+			return 50, false
+		}
+		return 100, false
+	}
+	return 100 - 100/(1+avgGain/avgLoss), false
+}
@@ -0,0 +1,119 @@
+package indicators
+
+import (
+	"github.com/godoji/candlestick"
+)
+
+// Stochastic is the stochastic oscillator. The raw %K is the position of
+// the close in the high low range of the last period candles, 50 when the
+// range is empty. The %K series is its SMA over smoothK values, and %D the
+// SMA of %K over smoothD values.
+func Stochastic(candles []candlestick.Candle, period int, smoothK int, smoothD int) *candlestick.Indicator {
+	ind := newIndicator("stochastic", period, smoothK, smoothD)
+	k := addSeries(ind, "k", len(candles), candlestick.LineChart, candlestick.CustomAxis)
+	d := addSeries(ind, "d", len(candles), candlestick.LineChart, candlestick.CustomAxis)
+	if period < 1 || smoothK < 1 || smoothD < 1 {
+		return ind
+	}
+	s := newStochasticState(period, smoothK, smoothD)
+	for i := range candles {
+		if candles[i].Missing {
+			continue
+		}
+		k[i], d[i] = s.update(&candles[i])
+	}
+	return ind
+}
+
+type stochasticState struct {
+	Highs *extremeState
+	Lows  *extremeState
+	K     *smaState
+	D     *smaState
+}
+
+func newStochasticState(period int, smoothK int, smoothD int) *stochasticState {
+	return &stochasticState{
+		Highs: newExtremeState(period, true),
+		Lows:  newExtremeState(period, false),
+		K:     newSMAState(smoothK),
+		D:     newSMAState(smoothD),
+	}
+}
+
+func (s *stochasticState) update(c *candlestick.Candle) (k, d candlestick.IndicatorValue) {
+	d.Missing = true
+	s.Highs.update(c.High)
+	s.Lows.update(c.Low)
+	if !s.Highs.full() {
+		k.Missing = true
+		return k, d
+	}
+
+	high := s.Highs.value()
+	low := s.Lows.value()
+	raw := 50.0
+	if high > low {
+		raw = 100 * (c.Close - low) / (high - low)
+	}
+
+	k.Value, k.Missing = s.K.update(raw)
+	if !k.Missing {
+		d.Value, d.Missing = s.D.update(k.Value)
+	}
+	return k, d
+}
+
+// extremeState tracks the maximum or minimum of the last period values with
+// a monotonic deque, in amortized constant time per value. The deque is a
+// ring buffer of the values that can still become the extreme, together
+// with their positions, the extreme at its head.
+type extremeState struct {
+	Max       bool
+	Values    []float64
+	Positions []int64
+	Head      int
+	Len       int
+	Position  int64
+}
+
+func newExtremeState(period int, max bool) *extremeState {
+	return &extremeState{
+		Max:       max,
+		Values:    make([]float64, period),
+		Positions: make([]int64, period),
+	}
+}
+
+func (s *extremeState) update(v float64) {
+	period := len(s.Values)
+
+	// drop the head once it leaves the window
+	if s.Len > 0 && s.Positions[s.Head] <= s.Position-int64(period) {
+		s.Head = (s.Head + 1) % period
+		s.Len--
+	}
+
+	// drop values that can no longer become the extreme
+	for s.Len > 0 {
+		back := s.Values[(s.Head+s.Len-1)%period]
+		if s.Max && back > v || !s.Max && back < v {
+			break
+		}
+		s.Len--
+	}
+
+	tail := (s.Head + s.Len) % period
+	s.Values[tail] = v
+	s.Positions[tail] = s.Position
+	s.Len++
+	s.Position++
+}
+
+func (s *extremeState) full() bool {
+	return s.Position >= int64(len(s.Values))
+}
+
+func (s *extremeState) value() float64 {
+	return s.Values[s.Head]
+}
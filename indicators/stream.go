@@ -0,0 +1,217 @@
+package indicators
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+
+	"github.com/godoji/candlestick"
+)
+
+var ErrNoStream = errors.New("indicators: indicator does not support streaming")
+var ErrOutOfOrder = errors.New("indicators: candle is not newer than the last one")
+
+// State is the incremental state of an indicator. Update consumes the next
+// present candle and writes one value per output, in the order of the
+// definition's outputs. States are serialized with encoding/gob, so all
+// fields needed to resume must be exported.
+type State interface {
+	Update(c *candlestick.Candle, values []candlestick.IndicatorValue)
+}
+
+// Stream computes an indicator one candle at a time, in constant time per
+// candle for the built-in indicators, amortized for the sliding high and
+// low of Stochastic. Feeding it the candles of a block gives the same
+// values as computing the indicator over them at once.
+type Stream struct {
+	meta       candlestick.IndicatorMeta
+	definition *Definition
+	state      State
+	values     []candlestick.IndicatorValue
+	time       int64
+	started    bool
+}
+
+// NewStream creates a stream for the indicator named by meta, with defaults
+// filled in for missing parameters as in Compute.
+func (r *Registry) NewStream(meta candlestick.IndicatorMeta) (*Stream, error) {
+	d, ok := r.Lookup(meta.Name)
+	if !ok {
+		return nil, ErrUnknownIndicator
+	}
+	if d.NewState == nil {
+		return nil, ErrNoStream
+	}
+	parameters, err := d.resolve(meta.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	meta.Parameters = parameters
+	meta.UID = UID(meta.Name, parameters)
+	return &Stream{
+		meta:       meta,
+		definition: d,
+		state:      d.NewState(parameters),
+		values:     make([]candlestick.IndicatorValue, len(d.Outputs)),
+	}, nil
+}
+
+// NewStream creates a stream for an indicator registered with
+// DefaultRegistry.
+func NewStream(meta candlestick.IndicatorMeta) (*Stream, error) {
+	return DefaultRegistry.NewStream(meta)
+}
+
+func (s *Stream) Meta() candlestick.IndicatorMeta {
+	return s.meta
+}
+
+// Time returns the time stamp of the last candle consumed, which is where a
+// resumed stream continues.
+func (s *Stream) Time() (int64, bool) {
+	return s.time, s.started
+}
+
+// Update consumes the next closed candle and returns the value of the
+// first output. Missing candles leave the state untouched and produce
+// missing values.
+func (s *Stream) Update(c candlestick.Candle) candlestick.IndicatorValue {
+	s.started = true
+	s.time = c.Time
+	if c.Missing {
+		for i := range s.values {
+			s.values[i] = candlestick.IndicatorValue{Missing: true}
+		}
+	} else {
+		s.state.Update(&c, s.values)
+	}
+	return s.values[0]
+}
+
+// Value returns the last value of the named output.
+func (s *Stream) Value(output string) (candlestick.IndicatorValue, bool) {
+	for i, o := range s.definition.Outputs {
+		if o.Name == output {
+			return s.values[i], true
+		}
+	}
+	return candlestick.IndicatorValue{}, false
+}
+
+// Append consumes the next closed candle and appends the values of all
+// outputs to ind, creating missing series as needed. Candles must be newer
+// than the last one consumed.
+func (s *Stream) Append(ind *candlestick.Indicator, c candlestick.Candle) error {
+	if s.started && c.Time <= s.time {
+		return ErrOutOfOrder
+	}
+	s.Update(c)
+	if ind.Series == nil {
+		ind.Series = make(map[string]*candlestick.IndicatorSeries)
+	}
+	for i, o := range s.definition.Outputs {
+		series, ok := ind.Series[o.Name]
+		if !ok {
+			series = &candlestick.IndicatorSeries{Kind: o.Kind, Axis: o.Axis}
+			ind.Series[o.Name] = series
+		}
+		series.Values = append(series.Values, s.values[i])
+	}
+	if c.Time > ind.Meta.LastUpdate {
+		ind.Meta.LastUpdate = c.Time
+	}
+	return nil
+}
+
+type checkpoint struct {
+	Meta    candlestick.IndicatorMeta
+	Time    int64
+	Started bool
+	State   []byte
+}
+
+// Checkpoint serializes the stream, so that it can be stored alongside the
+// block it was last updated with and resumed with ResumeStream.
+func (s *Stream) Checkpoint() ([]byte, error) {
+	var state bytes.Buffer
+	err := gob.NewEncoder(&state).Encode(s.state)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = gob.NewEncoder(&buf).Encode(checkpoint{
+		Meta:    s.meta,
+		Time:    s.time,
+		Started: s.started,
+		State:   state.Bytes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ResumeStream restores a stream from a checkpoint.
+func (r *Registry) ResumeStream(data []byte) (*Stream, error) {
+	var cp checkpoint
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cp)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.NewStream(cp.Meta)
+	if err != nil {
+		return nil, err
+	}
+	err = gob.NewDecoder(bytes.NewReader(cp.State)).Decode(s.state)
+	if err != nil {
+		return nil, err
+	}
+	s.meta = cp.Meta
+	s.time = cp.Time
+	s.started = cp.Started
+	return s, nil
+}
+
+// ResumeStream restores a stream of an indicator registered with
+// DefaultRegistry.
+func ResumeStream(data []byte) (*Stream, error) {
+	return DefaultRegistry.ResumeStream(data)
+}
+
+// The states of the built-in indicators, adapted to State.
+
+type smaStream struct{ SMA *smaState }
+type emaStream struct{ EMA *emaState }
+type rsiStream struct{ RSI *rsiState }
+type macdStream struct{ MACD *macdState }
+type bollingerStream struct{ Bollinger *bollingerState }
+type atrStream struct{ ATR *atrState }
+type stochasticStream struct{ Stochastic *stochasticState }
+
+func (s *smaStream) Update(c *candlestick.Candle, values []candlestick.IndicatorValue) {
+	values[0].Value, values[0].Missing = s.SMA.update(c.Close)
+}
+
+func (s *emaStream) Update(c *candlestick.Candle, values []candlestick.IndicatorValue) {
+	values[0].Value, values[0].Missing = s.EMA.update(c.Close)
+}
+
+func (s *rsiStream) Update(c *candlestick.Candle, values []candlestick.IndicatorValue) {
+	values[0].Value, values[0].Missing = s.RSI.update(c.Close)
+}
+
+func (s *macdStream) Update(c *candlestick.Candle, values []candlestick.IndicatorValue) {
+	values[0], values[1], values[2] = s.MACD.update(c.Close)
+}
+
+func (s *bollingerStream) Update(c *candlestick.Candle, values []candlestick.IndicatorValue) {
+	values[0], values[1], values[2] = s.Bollinger.update(c.Close)
+}
+
+func (s *atrStream) Update(c *candlestick.Candle, values []candlestick.IndicatorValue) {
+	values[0].Value, values[0].Missing = s.ATR.update(c)
+}
+
+func (s *stochasticStream) Update(c *candlestick.Candle, values []candlestick.IndicatorValue) {
+	values[0], values[1] = s.Stochastic.update(c)
+}
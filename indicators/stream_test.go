@@ -0,0 +1,95 @@
+package indicators
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/godoji/candlestick"
+)
+
+func TestStream(t *testing.T) {
+	candles := walkCandles(1000)
+	for _, name := range DefaultRegistry.Names() {
+		meta := candlestick.IndicatorMeta{Name: name, Interval: candlestick.Interval1m}
+		expected, err := Compute(meta, candles)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		// stream the first half, then resume from a checkpoint
+		stream, err := NewStream(meta)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		ind := &candlestick.Indicator{Meta: stream.Meta()}
+		for i := range candles {
+			if i == 500 {
+				cp, err := stream.Checkpoint()
+				if err != nil {
+					log.Fatalln(err)
+				}
+				stream, err = ResumeStream(cp)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				if last, ok := stream.Time(); !ok || last != candles[499].Time {
+					fmt.Printf("%s: resumed at %d\n", name, last)
+					t.FailNow()
+				}
+			}
+			if err = stream.Append(ind, candles[i]); err != nil {
+				log.Fatalln(err)
+			}
+			d, _ := DefaultRegistry.Lookup(name)
+			if v, _ := stream.Value(d.Outputs[0].Name); v != ind.Series[d.Outputs[0].Name].Values[i] {
+				fmt.Printf("%s: value %d does not match appended value\n", name, i)
+				t.FailNow()
+			}
+		}
+		if ind.Meta.UID != expected.Meta.UID {
+			fmt.Printf("%s: unexpected uid %s\n", name, ind.Meta.UID)
+			t.FailNow()
+		}
+		for key, series := range expected.Series {
+			if !series.Equal(ind.Series[key]) {
+				fmt.Printf("%s: streamed series %s did not match\n", name, key)
+				t.FailNow()
+			}
+		}
+
+		if err = stream.Append(ind, candles[10]); err != ErrOutOfOrder {
+			fmt.Printf("%s: expected out of order, got %v\n", name, err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestStreamUnsupported(t *testing.T) {
+	r := NewRegistry()
+	err := r.Register(Definition{
+		Name: "batch",
+		Compute: func(candles []candlestick.Candle, p []int) *candlestick.Indicator {
+			return SMA(candles, 1)
+		},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if _, err = r.NewStream(candlestick.IndicatorMeta{Name: "batch"}); err != ErrNoStream {
+		fmt.Printf("expected no stream, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func BenchmarkStreamUpdate(b *testing.B) {
+	candles := walkCandles(int(candlestick.CandleSetSize))
+	stream, err := NewStream(candlestick.IndicatorMeta{Name: "rsi"})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream.Update(candles[i%len(candles)])
+	}
+}
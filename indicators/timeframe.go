@@ -0,0 +1,180 @@
+package indicators
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/godoji/candlestick"
+)
+
+var ErrBaseInterval = errors.New("indicators: base interval is not a multiple of the interval")
+
+// Project maps indicators computed over blocks of the higher interval
+// baseInterval onto the block of the lower interval with n candles. Each
+// lower candle only sees the last higher candle that closed by the time it
+// closed itself, that is the higher candle starting at T for which
+// T + baseInterval <= t + interval is the latest, where t is the start of
+// the lower candle. Its values are missing when that higher value is
+// missing or not among the given indicators, or when the higher candle had
+// not closed by the LastUpdate of an incomplete higher block.
+func Project(higher []*candlestick.Indicator, baseInterval int64, interval int64, block int64, n int) (*candlestick.Indicator, error) {
+	if interval <= 0 || baseInterval < interval || baseInterval%interval != 0 {
+		return nil, ErrBaseInterval
+	}
+	sets := make(map[int64]*candlestick.Indicator, len(higher))
+	for _, ind := range higher {
+		if ind.Meta.Interval != baseInterval {
+			return nil, candlestick.ErrMetaMismatch
+		}
+		sets[ind.Meta.Block] = ind
+	}
+
+	// take series descriptors from any of the higher indicators
+	result := &candlestick.Indicator{
+		Series: make(map[string]*candlestick.IndicatorSeries),
+		Meta: candlestick.IndicatorMeta{
+			Block:        block,
+			Interval:     interval,
+			BaseInterval: baseInterval,
+		},
+	}
+	sorted := make([]*candlestick.Indicator, len(higher))
+	copy(sorted, higher)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Meta.Block < sorted[j].Meta.Block
+	})
+	if len(sorted) > 0 {
+		latest := sorted[len(sorted)-1]
+		result.Meta.Symbol = latest.Meta.Symbol
+		result.Meta.Name = latest.Meta.Name
+		result.Meta.Parameters = latest.Meta.Parameters
+		for key, series := range latest.Series {
+			result.Series[key] = &candlestick.IndicatorSeries{
+				Values:  make([]candlestick.IndicatorValue, n),
+				Kind:    series.Kind,
+				Axis:    series.Axis,
+				Storage: series.Storage,
+				Scale:   series.Scale,
+			}
+		}
+	}
+
+	// map every lower candle onto the last closed higher candle
+	first := candlestick.BlockToUnix(block, interval)
+	for i := 0; i < n; i++ {
+		t := first + int64(i)*interval
+		start := floorDiv(t+interval-baseInterval, baseInterval) * baseInterval
+		ind := sets[candlestick.UnixToBlock(start, baseInterval)]
+
+		// an incomplete higher block may end in a candle that had not
+		// closed yet when it was last updated
+		if ind != nil && !ind.Meta.Complete && ind.Meta.LastUpdate < start+baseInterval {
+			ind = nil
+		}
+		for key, series := range result.Series {
+			series.Values[i] = candlestick.IndicatorValue{Missing: true}
+			if ind == nil {
+				continue
+			}
+			higherSeries, ok := ind.Series[key]
+			if !ok {
+				continue
+			}
+			if index := ind.Index(start); index < int64(len(higherSeries.Values)) {
+				series.Values[i] = higherSeries.Values[index]
+			}
+		}
+	}
+	return result, nil
+}
+
+// ComputeMultiTimeframe computes the indicator named by meta at
+// meta.BaseInterval and projects it onto the candle block meta.Block at the
+// lower meta.Interval with Project, without lookahead. Both intervals are
+// read from source, and the higher blocks are computed with ComputeBlock
+// from historyStart on. The UID carries the base interval, as in
+// "rsi_14@86400", to tell the result apart from the indicator computed at
+// meta.Interval.
+func (r *Registry) ComputeMultiTimeframe(source candlestick.BlockSource, symbol candlestick.AssetIdentifier, meta candlestick.IndicatorMeta, historyStart int64) (*candlestick.Indicator, error) {
+	if meta.Interval <= 0 || meta.BaseInterval < meta.Interval || meta.BaseInterval%meta.Interval != 0 {
+		return nil, ErrBaseInterval
+	}
+	d, ok := r.Lookup(meta.Name)
+	if !ok {
+		return nil, ErrUnknownIndicator
+	}
+	parameters, err := d.resolve(meta.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := source.GetCandleSet(symbol, meta.Interval, meta.Block)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Meta.Block != meta.Block || cs.Meta.Interval != meta.Interval {
+		return nil, candlestick.ErrMetaMismatch
+	}
+
+	// compute the higher blocks the lower candles can see
+	var higher []*candlestick.Indicator
+	n := int64(len(cs.Candles))
+	if n > 0 {
+		first := floorDiv(cs.UnixFirst()+meta.Interval-meta.BaseInterval, meta.BaseInterval) * meta.BaseInterval
+		last := floorDiv(cs.TimeStampAtIndex(n-1)+meta.Interval-meta.BaseInterval, meta.BaseInterval) * meta.BaseInterval
+		for block := candlestick.UnixToBlock(first, meta.BaseInterval); block <= candlestick.UnixToBlock(last, meta.BaseInterval); block++ {
+			m := meta
+			m.Interval = meta.BaseInterval
+			m.Block = block
+			ind, err := r.ComputeBlock(source, symbol, m, historyStart)
+			if errors.Is(err, candlestick.ErrBlockNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			higher = append(higher, ind)
+		}
+	}
+
+	result, err := Project(higher, meta.BaseInterval, meta.Interval, meta.Block, int(n))
+	if err != nil {
+		return nil, err
+	}
+
+	// fill in series descriptors when no higher block was available
+	for _, o := range d.Outputs {
+		if _, ok := result.Series[o.Name]; ok {
+			continue
+		}
+		values := make([]candlestick.IndicatorValue, n)
+		for i := range values {
+			values[i].Missing = true
+		}
+		result.Series[o.Name] = &candlestick.IndicatorSeries{Values: values, Kind: o.Kind, Axis: o.Axis}
+	}
+
+	result.Meta.Name = meta.Name
+	result.Meta.Parameters = parameters
+	result.Meta.UID = UID(meta.Name, parameters) + "@" + strconv.FormatInt(meta.BaseInterval, 10)
+	result.Meta.Symbol = cs.Meta.Symbol
+	result.Meta.Complete = cs.Meta.Complete
+	result.Meta.LastUpdate = cs.Meta.LastUpdate
+	return result, nil
+}
+
+// ComputeMultiTimeframe computes an indicator registered with
+// DefaultRegistry on a higher timeframe, see
+// Registry.ComputeMultiTimeframe.
+func ComputeMultiTimeframe(source candlestick.BlockSource, symbol candlestick.AssetIdentifier, meta candlestick.IndicatorMeta, historyStart int64) (*candlestick.Indicator, error) {
+	return DefaultRegistry.ComputeMultiTimeframe(source, symbol, meta, historyStart)
+}
+
+// floorDiv divides a by b, rounding towards negative infinity.
+func floorDiv(a int64, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
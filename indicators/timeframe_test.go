@@ -0,0 +1,155 @@
+package indicators
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/godoji/candlestick"
+)
+
+func TestComputeMultiTimeframe(t *testing.T) {
+
+	// three blocks of hourly candles, the last one ending mid-day, and
+	// the daily candles resampled from them
+	hourly := walkCandles(3 * int(candlestick.CandleSetSize))
+	hourly = hourly[:len(hourly)-int(candlestick.CandleSetSize)+1000+7]
+	var sets []*candlestick.CandleSet
+	for b := int64(0); b < 3; b++ {
+		end := (b + 1) * candlestick.CandleSetSize
+		if end > int64(len(hourly)) {
+			end = int64(len(hourly))
+		}
+		cs := &candlestick.CandleSet{
+			Candles: hourly[b*candlestick.CandleSetSize : end],
+			Meta: candlestick.DataSetMeta{
+				Block:    b,
+				Complete: end-b*candlestick.CandleSetSize == candlestick.CandleSetSize,
+				Symbol:   "AAPLUSD",
+				Interval: candlestick.Interval1h,
+			},
+		}
+		for i := range cs.Candles {
+			cs.Candles[i].Time = cs.TimeStampAtIndex(int64(i))
+		}
+		cs.Meta.LastUpdate = cs.TimeStampAtIndex(int64(len(cs.Candles)))
+		sets = append(sets, cs)
+	}
+	daily, err := candlestick.Resample(sets, candlestick.Interval1d)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	blocks := map[int64]map[int64]*candlestick.CandleSet{
+		candlestick.Interval1h: {},
+		candlestick.Interval1d: {},
+	}
+	for _, cs := range append(sets, daily...) {
+		blocks[cs.Meta.Interval][cs.Meta.Block] = cs
+	}
+	source := candlestick.BlockSourceFunc(func(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+		if cs, ok := blocks[interval][block]; ok {
+			return cs, nil
+		}
+		return nil, candlestick.ErrBlockNotFound
+	})
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+
+	// the last daily candle is partial and must never be seen
+	if len(daily) != 1 || len(daily[0].Candles) != len(hourly)/24+1 {
+		fmt.Printf("unexpected daily candles\n")
+		t.FailNow()
+	}
+	expected := RSI(daily[0].Candles, 14).Series["rsi"].Values
+
+	for b := int64(0); b < 3; b++ {
+		meta := candlestick.IndicatorMeta{
+			Block:        b,
+			Interval:     candlestick.Interval1h,
+			BaseInterval: candlestick.Interval1d,
+			Name:         "rsi",
+		}
+		ind, err := ComputeMultiTimeframe(source, symbol, meta, 0)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if ind.Meta.UID != "rsi_14@86400" || ind.Meta.Interval != candlestick.Interval1h ||
+			ind.Meta.BaseInterval != candlestick.Interval1d || ind.Meta.Complete != sets[b].Meta.Complete {
+			fmt.Printf("unexpected meta %v\n", ind.Meta)
+			t.FailNow()
+		}
+		values := ind.Series["rsi"].Values
+		if len(values) != len(sets[b].Candles) || ind.Series["rsi"].Kind != candlestick.LineChart {
+			fmt.Printf("unexpected series of block %d\n", b)
+			t.FailNow()
+		}
+		for i, v := range values {
+
+			// an hour sees the day it closes and the days before
+			hour := b*candlestick.CandleSetSize + int64(i)
+			day := (hour+1)/24 - 1
+			e := candlestick.IndicatorValue{Missing: true}
+			if day >= 0 {
+				e = expected[day]
+			}
+			if v != e {
+				fmt.Printf("block %d: value %d is %v, expected %v of day %d\n", b, i, v, e, day)
+				t.FailNow()
+			}
+		}
+	}
+
+	meta := candlestick.IndicatorMeta{Interval: candlestick.Interval1d, BaseInterval: candlestick.Interval1h, Name: "rsi"}
+	if _, err = ComputeMultiTimeframe(source, symbol, meta, 0); err != ErrBaseInterval {
+		fmt.Printf("expected base interval error, got %v\n", err)
+		t.FailNow()
+	}
+
+	// a daily block that lags the hourly blocks ends in a partial day
+	// that must not be seen as closed
+	lag := int64(len(hourly)) - 24*30 - 5
+	var stale []*candlestick.CandleSet
+	for _, cs := range sets {
+		if cs.UnixFirst() >= candlestick.BlockToUnix(0, candlestick.Interval1h)+lag*candlestick.Interval1h {
+			continue
+		}
+		trimmed := *cs
+		if end := lag - cs.Meta.Block*candlestick.CandleSetSize; end < int64(len(cs.Candles)) {
+			trimmed.Candles = cs.Candles[:end]
+			trimmed.Meta.Complete = false
+			trimmed.Meta.LastUpdate = cs.TimeStampAtIndex(end)
+		}
+		stale = append(stale, &trimmed)
+	}
+	staleDaily, err := candlestick.Resample(stale, candlestick.Interval1d)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	blocks[candlestick.Interval1d][0] = staleDaily[0]
+	lastClosedDay := lag/24 - 1
+	if int64(len(staleDaily[0].Candles)) != lastClosedDay+2 {
+		fmt.Printf("stale daily block should end in a partial day\n")
+		t.FailNow()
+	}
+	meta = candlestick.IndicatorMeta{
+		Block:        2,
+		Interval:     candlestick.Interval1h,
+		BaseInterval: candlestick.Interval1d,
+		Name:         "rsi",
+	}
+	ind, err := ComputeMultiTimeframe(source, symbol, meta, 0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for i, v := range ind.Series["rsi"].Values {
+		hour := 2*candlestick.CandleSetSize + int64(i)
+		day := (hour+1)/24 - 1
+		e := candlestick.IndicatorValue{Missing: true}
+		if day <= lastClosedDay {
+			e = expected[day]
+		}
+		if v != e {
+			fmt.Printf("stale block: value %d is %v, expected %v of day %d\n", i, v, e, day)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package candlestick
+
+// MergeCandleSets merges a partial update into a copy of base and returns
+// it together with the indices whose candles changed. Both blocks must
+// belong to the same symbol, interval and block, and carry the same UID
+// when both have one. For each index:
+//
+//   - a missing candle never replaces a present one,
+//   - a present candle always replaces a missing one,
+//   - when both are present, the candle of the block with the newer
+//     LastUpdate wins, the update on a tie.
+//
+// The result is as long as the longer of both blocks, complete when either
+// is and carries the newest LastUpdate.
+func MergeCandleSets(base *CandleSet, update *CandleSet) (*CandleSet, []int64, error) {
+
+	// validate meta data
+	if base.Meta.Symbol != update.Meta.Symbol || base.Meta.Interval != update.Meta.Interval ||
+		base.Meta.Block != update.Meta.Block {
+		return nil, nil, ErrMetaMismatch
+	}
+	if base.Meta.UID != "" && update.Meta.UID != "" && base.Meta.UID != update.Meta.UID {
+		return nil, nil, ErrMetaMismatch
+	}
+
+	// merge meta data
+	meta := base.Meta
+	if meta.UID == "" {
+		meta.UID = update.Meta.UID
+	}
+	meta.Complete = base.Meta.Complete || update.Meta.Complete
+	updateIsNewer := update.Meta.LastUpdate >= base.Meta.LastUpdate
+	if updateIsNewer {
+		meta.LastUpdate = update.Meta.LastUpdate
+	}
+
+	// merge candles
+	n := len(base.Candles)
+	if len(update.Candles) > n {
+		n = len(update.Candles)
+	}
+	candles := make([]Candle, n)
+	copy(candles, base.Candles)
+	var changed []int64
+	for i := range update.Candles {
+		c := &update.Candles[i]
+		if i < len(base.Candles) {
+			b := &base.Candles[i]
+			if c.Missing || !b.Missing && !updateIsNewer || *b == *c {
+				continue
+			}
+		}
+		candles[i] = *c
+		changed = append(changed, int64(i))
+	}
+
+	return &CandleSet{
+		Candles: candles,
+		Meta:    meta,
+	}, changed, nil
+}
@@ -0,0 +1,64 @@
+package candlestick
+
+import (
+	"fmt"
+	"log"
+	"testing"
+)
+
+func TestMergeCandleSets(t *testing.T) {
+	base := walkCandleSet()
+	base.Candles = base.Candles[:100]
+	base.Meta.Complete = false
+	update := &CandleSet{
+		Candles: make([]Candle, 120),
+		Meta:    base.Meta,
+	}
+	update.Meta.LastUpdate++
+	copy(update.Candles, base.Candles)
+	update.Candles[5] = Candle{Time: base.TimeStampAtIndex(5), Missing: true}
+	update.Candles[13].Missing = false
+	update.Candles[13].Close = 42
+	update.Candles[50].Close = 43
+	for i := 100; i < 120; i++ {
+		update.Candles[i] = Candle{Close: float64(i), Time: base.TimeStampAtIndex(int64(i))}
+	}
+
+	merged, changed, err := MergeCandleSets(base, update)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(merged.Candles) != 120 || merged.Meta.LastUpdate != update.Meta.LastUpdate || len(changed) != 22 {
+		fmt.Printf("unexpected merge result: %d candles, changed %v\n", len(merged.Candles), changed)
+		t.FailNow()
+	}
+	if merged.Candles[5] != base.Candles[5] || merged.Candles[13].Close != 42 || merged.Candles[50].Close != 43 || merged.Candles[110].Close != 110 {
+		fmt.Printf("unexpected merged candles\n")
+		t.FailNow()
+	}
+	if changed[0] != 13 || changed[1] != 50 || changed[2] != 100 {
+		fmt.Printf("unexpected changed indices %v\n", changed)
+		t.FailNow()
+	}
+	if base.Candles[13].Close == 42 || len(base.Candles) != 100 {
+		fmt.Printf("base was modified\n")
+		t.FailNow()
+	}
+
+	// older updates only fill in missing candles
+	update.Meta.LastUpdate = base.Meta.LastUpdate - 1
+	merged, _, err = MergeCandleSets(base, update)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if merged.Candles[13].Close != 42 || merged.Candles[50].Close == 43 || merged.Meta.LastUpdate != base.Meta.LastUpdate {
+		fmt.Printf("older update overwrote newer candles\n")
+		t.FailNow()
+	}
+
+	update.Meta.Block++
+	if _, _, err = MergeCandleSets(base, update); err != ErrMetaMismatch {
+		fmt.Printf("expected meta mismatch, got %v\n", err)
+		t.FailNow()
+	}
+}
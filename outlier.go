@@ -0,0 +1,251 @@
+package candlestick
+
+import (
+	"math"
+	"sort"
+)
+
+type OutlierMethod uint8
+
+const (
+	// OutlierMAD scores values by their distance from the rolling median
+	// in units of the scaled median absolute deviation, or of the mean
+	// absolute deviation where that is larger.
+	OutlierMAD OutlierMethod = iota
+	// OutlierZScore scores values by their distance from the rolling mean
+	// in units of the standard deviation.
+	OutlierZScore
+)
+
+const (
+	OutlierIndicatorName = "outlier"
+	OutlierFlagSeries    = "flag"
+	OutlierScoreSeries   = "score"
+)
+
+// maxExcludedOutliers is the number of values in a row that are left out
+// of a window for exceeding the threshold. Later values enter the window
+// regardless, so that it adapts to lasting changes in volatility.
+const maxExcludedOutliers = 3
+
+// minOutlierSpread is the smallest spread a window is assumed to have, a
+// thousandth of a percent of the price. It keeps scores finite on flat
+// stretches, where any deviation would otherwise score infinitely high.
+const minOutlierSpread = 1e-5
+
+// madScale makes the median absolute deviation of normally distributed
+// data an estimate of its standard deviation.
+const madScale = 1.4826
+
+type OutlierOptions struct {
+	Method OutlierMethod
+	// Window is the number of preceding present candles each candle is
+	// compared to, 50 when zero.
+	Window int
+	// Threshold is the score above which a candle is flagged, 5 when
+	// zero.
+	Threshold float64
+}
+
+// DetectOutliers flags candles whose upper wick, lower wick or close to
+// close return deviate from those of the preceding candles by more than
+// the threshold. Wicks are measured relative to the candle body.
+//
+// The result holds a BarChart series with 1 for flagged candles and 0
+// otherwise, and a LineChart series with the highest score of each
+// candle. Missing candles and candles before the window is filled are
+// missing in both. Values exceeding the threshold are left out of their
+// window, so that a spike does not mask the candles that follow it, but at
+// most maxExcludedOutliers in a row. The parameters are the method, the
+// window and the threshold in hundredths.
+func DetectOutliers(candles []Candle, options OutlierOptions) *Indicator {
+	if options.Window <= 0 {
+		options.Window = 50
+	}
+	if options.Threshold <= 0 {
+		options.Threshold = 5
+	}
+
+	flags := make([]IndicatorValue, len(candles))
+	scores := make([]IndicatorValue, len(candles))
+	windows := [3]rollingWindow{
+		newRollingWindow(options.Window),
+		newRollingWindow(options.Window),
+		newRollingWindow(options.Window),
+	}
+	prevClose := math.NaN()
+	var metrics [3]float64
+	for i := range candles {
+		c := &candles[i]
+		if c.Missing {
+			flags[i].Missing = true
+			scores[i].Missing = true
+			continue
+		}
+
+		// measure candle
+		top := math.Max(c.Open, c.Close)
+		bottom := math.Min(c.Open, c.Close)
+		metrics[0] = relativeChange(c.High, top)
+		metrics[1] = relativeChange(bottom, c.Low)
+		metrics[2] = relativeChange(c.Close, prevClose)
+		prevClose = c.Close
+
+		// score against window
+		score := 0.0
+		warm := true
+		var exceeded [3]bool
+		for j, m := range metrics {
+			if math.IsNaN(m) {
+				continue
+			}
+			if !windows[j].full() {
+				warm = false
+				continue
+			}
+			metricScore := windows[j].score(m, options.Method)
+			exceeded[j] = metricScore > options.Threshold
+			score = math.Max(score, metricScore)
+		}
+		flagged := warm && score > options.Threshold
+
+		// leave out values that exceed the threshold, unless the window
+		// has to follow a lasting change
+		for j, m := range metrics {
+			if math.IsNaN(m) {
+				continue
+			}
+			if exceeded[j] && windows[j].excluded < maxExcludedOutliers {
+				windows[j].excluded++
+				continue
+			}
+			windows[j].excluded = 0
+			windows[j].push(m)
+		}
+		if !warm {
+			flags[i].Missing = true
+			scores[i].Missing = true
+			continue
+		}
+		scores[i].Value = score
+		if flagged {
+			flags[i].Value = 1
+		}
+	}
+
+	return &Indicator{
+		Series: map[string]*IndicatorSeries{
+			OutlierFlagSeries: {
+				Values: flags,
+				Kind:   BarChart,
+				Axis:   CustomAxis,
+			},
+			OutlierScoreSeries: {
+				Values: scores,
+				Kind:   LineChart,
+				Axis:   CustomAxis,
+			},
+		},
+		Meta: IndicatorMeta{
+			Name:       OutlierIndicatorName,
+			Parameters: []int{int(options.Method), options.Window, int(math.Round(options.Threshold * 100))},
+		},
+	}
+}
+
+// DetectCandleSetOutliers runs DetectOutliers over a block and fills in the
+// indicator meta data from the block.
+func DetectCandleSetOutliers(cs *CandleSet, options OutlierOptions) *Indicator {
+	ind := DetectOutliers(cs.Candles, options)
+	ind.Meta.Block = cs.Meta.Block
+	ind.Meta.Complete = cs.Meta.Complete
+	ind.Meta.LastUpdate = cs.Meta.LastUpdate
+	ind.Meta.Symbol = cs.Meta.Symbol
+	ind.Meta.Interval = cs.Meta.Interval
+	ind.Meta.BaseInterval = cs.Meta.Interval
+	return ind
+}
+
+// relativeChange returns (a - b) / |b|, or NaN when b is zero or NaN.
+func relativeChange(a float64, b float64) float64 {
+	if b == 0 || math.IsNaN(b) {
+		return math.NaN()
+	}
+	return (a - b) / math.Abs(b)
+}
+
+// rollingWindow holds the last values of a metric in a ring buffer.
+type rollingWindow struct {
+	values   []float64
+	next     int
+	n        int
+	excluded int
+	scratch  []float64
+}
+
+func newRollingWindow(size int) rollingWindow {
+	return rollingWindow{
+		values:  make([]float64, size),
+		scratch: make([]float64, size),
+	}
+}
+
+func (w *rollingWindow) full() bool {
+	return w.n == len(w.values)
+}
+
+func (w *rollingWindow) push(v float64) {
+	w.values[w.next] = v
+	w.next = (w.next + 1) % len(w.values)
+	if w.n < len(w.values) {
+		w.n++
+	}
+}
+
+// score returns the distance of v from the window's center in units of its
+// spread, which is at least minOutlierSpread.
+func (w *rollingWindow) score(v float64, method OutlierMethod) float64 {
+	var center, spread float64
+	switch method {
+	case OutlierZScore:
+		for _, x := range w.values {
+			center += x
+		}
+		center /= float64(len(w.values))
+		for _, x := range w.values {
+			spread += (x - center) * (x - center)
+		}
+		spread = math.Sqrt(spread / float64(len(w.values)))
+	default:
+		center = w.median(w.values)
+		for i, x := range w.values {
+			w.scratch[i] = math.Abs(x - center)
+		}
+		spread = madScale * w.median(w.scratch)
+
+		// the median absolute deviation collapses when most values equal
+		// the median, as with wicks of a whole number of ticks, so it is
+		// bounded by the mean absolute deviation
+		var mean float64
+		for _, x := range w.values {
+			mean += math.Abs(x - center)
+		}
+		spread = math.Max(spread, mean/float64(len(w.values))*math.Sqrt(math.Pi/2))
+	}
+
+	return math.Abs(v-center) / math.Max(spread, minOutlierSpread)
+}
+
+// median returns the median of values, using scratch as buffer when values
+// is not scratch itself.
+func (w *rollingWindow) median(values []float64) float64 {
+	if &values[0] != &w.scratch[0] {
+		copy(w.scratch, values)
+	}
+	sort.Float64s(w.scratch)
+	n := len(w.scratch)
+	if n%2 == 1 {
+		return w.scratch[n/2]
+	}
+	return (w.scratch[n/2-1] + w.scratch[n/2]) / 2
+}
@@ -0,0 +1,117 @@
+package candlestick
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDetectOutliers(t *testing.T) {
+	for _, method := range []OutlierMethod{OutlierMAD, OutlierZScore} {
+		cs := walkCandleSet()
+		cs.Candles = cs.Candles[:1000]
+
+		// fat finger wick and close spike
+		cs.Candles[300].High = cs.Candles[300].Close * 1.5
+		cs.Candles[600].Low = cs.Candles[600].Close * 0.5
+		cs.Candles[800].Close *= 1.2
+		cs.Candles[800].High = cs.Candles[800].Close
+
+		ind := DetectCandleSetOutliers(cs, OutlierOptions{Method: method, Threshold: 8})
+		flags := ind.Series[OutlierFlagSeries]
+		if flags.Kind != BarChart || len(flags.Values) != len(cs.Candles) || ind.Meta.Name != OutlierIndicatorName ||
+			ind.Meta.Block != cs.Meta.Block || ind.Meta.Parameters[2] != 800 {
+			fmt.Printf("unexpected indicator\n")
+			t.FailNow()
+		}
+		for i, v := range flags.Values {
+			switch {
+			case i < 52:
+				// the window is filled by the first 50 present candles
+				// and returns start at the second
+				if !v.Missing && i < 51 {
+					fmt.Printf("method %d: candle %d should be missing\n", method, i)
+					t.FailNow()
+				}
+			case cs.Candles[i].Missing:
+				if !v.Missing {
+					fmt.Printf("method %d: candle %d should be missing\n", method, i)
+					t.FailNow()
+				}
+			case i == 300 || i == 600 || i == 800:
+				if v.Missing || v.Value != 1 {
+					fmt.Printf("method %d: candle %d should be flagged, score %v\n", method, i, ind.Series[OutlierScoreSeries].Values[i])
+					t.FailNow()
+				}
+			case i == 801:
+				// the close spike reverts in the next candle
+			default:
+				if v.Missing || v.Value != 0 {
+					fmt.Printf("method %d: candle %d should not be flagged, score %v\n", method, i, ind.Series[OutlierScoreSeries].Values[i])
+					t.FailNow()
+				}
+			}
+		}
+	}
+}
+
+func TestDetectOutliersFlat(t *testing.T) {
+	candles := make([]Candle, 100)
+	for i := range candles {
+		candles[i] = Candle{Open: 10, High: 10, Low: 10, Close: 10}
+	}
+	candles[80].High = 10.01
+	ind := DetectOutliers(candles, OutlierOptions{Window: 20})
+	flags := ind.Series[OutlierFlagSeries].Values
+	if !flags[19].Missing || flags[21].Missing || flags[21].Value != 0 || flags[80].Value != 1 {
+		fmt.Printf("unexpected flags on flat candles\n")
+		t.FailNow()
+	}
+
+	// the wick is a hundred times the minimum spread
+	if score := ind.Series[OutlierScoreSeries].Values[80]; math.Abs(score.Value-100) > 1e-6 {
+		fmt.Printf("unexpected score %v on flat candles\n", score)
+		t.FailNow()
+	}
+	_, err := json.Marshal(ind)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func TestDetectOutliersVolatilityChange(t *testing.T) {
+	for _, method := range []OutlierMethod{OutlierMAD, OutlierZScore} {
+		cs := walkCandleSet()
+		cs.Candles = cs.Candles[:1000]
+
+		// from candle 500 on, prices move and wick fifty times as much
+		price := cs.Candles[499].Close
+		for i := 500; i < len(cs.Candles); i++ {
+			if cs.Candles[i].Missing {
+				continue
+			}
+			c := &cs.Candles[i]
+			open := price
+			price *= 1 + float64(rand.Intn(21)-10)*0.005
+			c.Open = open
+			c.Close = price
+			c.High = math.Max(open, price) * (1 + float64(rand.Intn(3))*0.005)
+			c.Low = math.Min(open, price) * (1 - float64(rand.Intn(3))*0.005)
+		}
+
+		ind := DetectOutliers(cs.Candles, OutlierOptions{Method: method, Threshold: 8})
+		flagged := 0
+		for _, v := range ind.Series[OutlierFlagSeries].Values[700:] {
+			if !v.Missing && v.Value == 1 {
+				flagged++
+			}
+		}
+		if flagged > 5 {
+			fmt.Printf("method %d: %d candles flagged long after the volatility change\n", method, flagged)
+			t.FailNow()
+		}
+	}
+}
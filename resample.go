@@ -0,0 +1,163 @@
+package candlestick
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var ErrIntervalNotDerivable = errors.New("candlestick: interval cannot be derived from source interval")
+var ErrMetaMismatch = errors.New("candlestick: meta data does not match")
+
+// IntervalPath walks IntervalMap from target down to source and returns
+// the intervals in between, starting with the first interval derived from
+// source and ending with target.
+func IntervalPath(source int64, target int64) ([]int64, bool) {
+	var path []int64
+	for interval := target; interval != source; {
+		path = append(path, interval)
+		base, ok := IntervalMap[interval]
+		if !ok {
+			return nil, false
+		}
+		interval = base
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, true
+}
+
+// Resample aggregates blocks of one symbol and interval into blocks of the
+// target interval, which must be derivable from the source interval through
+// IntervalMap. Aggregating directly gives the same result as aggregating
+// step by step along the path, as every interval is a multiple of its base.
+//
+// A target candle is missing when none of its source candles are present.
+// A target block is complete when all source blocks it spans are present
+// and complete; incomplete blocks end at the last candle covered by source
+// data.
+func Resample(src []*CandleSet, target int64) ([]*CandleSet, error) {
+
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	// validate sources
+	sets := make([]*CandleSet, len(src))
+	copy(sets, src)
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Meta.Block < sets[j].Meta.Block
+	})
+	source := sets[0].Meta.Interval
+	for i, cs := range sets {
+		if cs.Meta.Interval != source || cs.Meta.Symbol != sets[0].Meta.Symbol {
+			return nil, ErrMetaMismatch
+		}
+		if i > 0 && cs.Meta.Block == sets[i-1].Meta.Block {
+			return nil, ErrMetaMismatch
+		}
+	}
+	if _, ok := IntervalPath(source, target); !ok {
+		return nil, ErrIntervalNotDerivable
+	}
+	sourceBlocks := make(map[int64]*CandleSet, len(sets))
+	for _, cs := range sets {
+		sourceBlocks[cs.Meta.Block] = cs
+	}
+
+	// create target blocks
+	var result []*CandleSet
+	blocks := make(map[int64]*CandleSet)
+	covered := make(map[int64]int64)
+	for _, cs := range sets {
+		first := UnixToBlock(cs.UnixFirst(), target)
+		last := UnixToBlock(cs.UnixLast(), target)
+		for block := first; block <= last; block++ {
+			if _, ok := blocks[block]; ok {
+				continue
+			}
+			out := newResampledBlock(cs.Meta.Symbol, target, block, source, sourceBlocks)
+			blocks[block] = out
+			covered[block] = -1
+			result = append(result, out)
+		}
+	}
+
+	// aggregate candles
+	for _, cs := range sets {
+		for i := range cs.Candles {
+			ts := cs.TimeStampAtIndex(int64(i))
+			block := UnixToBlock(ts, target)
+			out := blocks[block]
+			index := out.Index(ts)
+			if index > covered[block] {
+				covered[block] = index
+			}
+			c := &cs.Candles[i]
+			if c.Missing {
+				continue
+			}
+			aggregateCandle(&out.Candles[index], c)
+		}
+	}
+
+	// trim incomplete blocks
+	for _, out := range result {
+		if !out.Meta.Complete {
+			out.Candles = out.Candles[:covered[out.Meta.Block]+1]
+		}
+	}
+
+	return result, nil
+}
+
+func newResampledBlock(symbol string, interval int64, block int64, source int64, sourceBlocks map[int64]*CandleSet) *CandleSet {
+	out := &CandleSet{
+		Candles: make([]Candle, CandleSetSize),
+		Meta: DataSetMeta{
+			Block:    block,
+			Complete: true,
+			Symbol:   symbol,
+			Interval: interval,
+		},
+	}
+	for i := range out.Candles {
+		out.Candles[i] = Candle{
+			Time:    out.TimeStampAtIndex(int64(i)),
+			Missing: true,
+		}
+	}
+
+	// the block is complete when every source block it spans is
+	first := UnixToBlock(out.UnixFirst(), source)
+	last := UnixToBlock(out.UnixLast()+interval-source, source)
+	for b := first; b <= last; b++ {
+		cs, ok := sourceBlocks[b]
+		if !ok || !cs.Meta.Complete || int64(len(cs.Candles)) != CandleSetSize {
+			out.Meta.Complete = false
+		}
+		if ok && cs.Meta.LastUpdate > out.Meta.LastUpdate {
+			out.Meta.LastUpdate = cs.Meta.LastUpdate
+		}
+	}
+	return out
+}
+
+// aggregateCandle merges c, which follows all candles merged so far, into
+// the aggregate dst.
+func aggregateCandle(dst *Candle, c *Candle) {
+	if dst.Missing {
+		dst.Open = c.Open
+		dst.High = c.High
+		dst.Low = c.Low
+		dst.Missing = false
+	} else {
+		dst.High = math.Max(dst.High, c.High)
+		dst.Low = math.Min(dst.Low, c.Low)
+	}
+	dst.Close = c.Close
+	dst.Volume += c.Volume
+	dst.TakerVolume += c.TakerVolume
+	dst.NumberOfTrades += c.NumberOfTrades
+}
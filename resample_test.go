@@ -0,0 +1,104 @@
+package candlestick
+
+import (
+	"fmt"
+	"log"
+	"testing"
+)
+
+func walkBlocks(interval int64, blocks ...int64) []*CandleSet {
+	var sets []*CandleSet
+	for _, block := range blocks {
+		cs := walkCandleSet()
+		cs.Meta.Block = block
+		cs.Meta.Interval = interval
+		for i := range cs.Candles {
+			cs.Candles[i].Time = cs.TimeStampAtIndex(int64(i))
+		}
+		sets = append(sets, cs)
+	}
+	return sets
+}
+
+func TestIntervalPath(t *testing.T) {
+	path, ok := IntervalPath(Interval1m, Interval1d)
+	if !ok || len(path) != 7 || path[0] != Interval5m || path[6] != Interval1d {
+		fmt.Printf("unexpected path: %v\n", path)
+		t.FailNow()
+	}
+	if _, ok = IntervalPath(Interval45m, Interval1h); ok {
+		fmt.Printf("45m should not lead to 1h\n")
+		t.FailNow()
+	}
+}
+
+func TestResample(t *testing.T) {
+	src := walkBlocks(Interval1m, 1, 0)
+	out, err := Resample(src, Interval1h)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(out) != 1 || out[0].Meta.Block != 0 || out[0].Meta.Complete || len(out[0].Candles) != 167 {
+		fmt.Printf("unexpected target blocks\n")
+		t.FailNow()
+	}
+
+	// compare against a naive aggregation
+	var minutes []Candle
+	minutes = append(minutes, src[1].Candles...)
+	minutes = append(minutes, src[0].Candles...)
+	for i, c := range out[0].Candles {
+		expected := Candle{Time: int64(i) * Interval1h, Missing: true}
+		end := i*60 + 60
+		if end > len(minutes) {
+			end = len(minutes)
+		}
+		for _, m := range minutes[i*60 : end] {
+			if !m.Missing {
+				aggregateCandle(&expected, &m)
+			}
+		}
+		if c != expected {
+			fmt.Printf("hour %d did not match:\n", i)
+			fmt.Println(expected)
+			fmt.Println(c)
+			t.FailNow()
+		}
+	}
+
+	// 1m to 3d steps through the whole interval map
+	if _, err = Resample(src, Interval3d); err != nil {
+		log.Fatalln(err)
+	}
+	if _, err = Resample(walkBlocks(Interval45m, 0), Interval1h); err != ErrIntervalNotDerivable {
+		fmt.Printf("expected not derivable, got %v\n", err)
+		t.FailNow()
+	}
+}
+
+func TestResampleComplete(t *testing.T) {
+	src := walkBlocks(Interval1h, 0, 1)
+	src[0].Candles[20].Missing = true
+	src[0].Candles[21].Missing = true
+	src[0].Candles[22].Missing = true
+	out, err := Resample(src, Interval2h)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(out) != 1 || !out[0].Meta.Complete || int64(len(out[0].Candles)) != CandleSetSize {
+		fmt.Printf("expected one complete block\n")
+		t.FailNow()
+	}
+	if !out[0].Candles[10].Missing || out[0].Candles[11].Missing || out[0].Candles[11].Open != src[0].Candles[23].Open {
+		fmt.Printf("missing candles not propagated\n")
+		t.FailNow()
+	}
+	out, err = Resample(src[:1], Interval2h)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if out[0].Meta.Complete || int64(len(out[0].Candles)) != CandleSetSize/2 {
+		fmt.Printf("expected half an incomplete block\n")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,95 @@
+package candlestick
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrBlockNotFound = errors.New("candlestick: block not found")
+
+// maxConcurrentFetches bounds the number of blocks Range fetches at once.
+const maxConcurrentFetches = 8
+
+// BlockSource provides candle blocks by symbol, interval and block number.
+// Blocks that do not exist are reported with ErrBlockNotFound.
+type BlockSource interface {
+	GetCandleSet(symbol AssetIdentifier, interval int64, block int64) (*CandleSet, error)
+}
+
+type BlockSourceFunc func(symbol AssetIdentifier, interval int64, block int64) (*CandleSet, error)
+
+func (f BlockSourceFunc) GetCandleSet(symbol AssetIdentifier, interval int64, block int64) (*CandleSet, error) {
+	return f(symbol, interval, block)
+}
+
+// Range returns the candles of symbol with a time stamp in [from, to),
+// stitched together from all blocks the range spans. The blocks are fetched
+// concurrently. Candles of blocks that do not exist, or that lie past the
+// end of an incomplete block, are returned as missing.
+func Range(source BlockSource, symbol AssetIdentifier, interval int64, from int64, to int64) ([]Candle, error) {
+
+	// align range to interval
+	first := alignUp(from, interval)
+	if to <= first {
+		return nil, nil
+	}
+	n := (to - first + interval - 1) / interval
+	firstBlock := UnixToBlock(first, interval)
+	lastBlock := UnixToBlock(first+(n-1)*interval, interval)
+
+	// fetch blocks
+	sets := make([]*CandleSet, lastBlock-firstBlock+1)
+	errs := make([]error, len(sets))
+	sem := make(chan struct{}, maxConcurrentFetches)
+	var wg sync.WaitGroup
+	for i := range sets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			sets[i], errs[i] = source.GetCandleSet(symbol, interval, firstBlock+int64(i))
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if errors.Is(err, ErrBlockNotFound) {
+			sets[i] = nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sets[i].Meta.Block != firstBlock+int64(i) || sets[i].Meta.Interval != interval {
+			return nil, ErrMetaMismatch
+		}
+	}
+
+	// stitch candles
+	candles := make([]Candle, n)
+	for i := range candles {
+		ts := first + int64(i)*interval
+		cs := sets[UnixToBlock(ts, interval)-firstBlock]
+		if cs != nil {
+			if index := cs.Index(ts); index < int64(len(cs.Candles)) {
+				candles[i] = cs.Candles[index]
+				continue
+			}
+		}
+		candles[i] = Candle{Time: ts, Missing: true}
+	}
+
+	return candles, nil
+}
+
+// alignUp rounds unixTime up to a multiple of interval.
+func alignUp(unixTime int64, interval int64) int64 {
+	r := unixTime % interval
+	if r == 0 {
+		return unixTime
+	}
+	if r < 0 {
+		return unixTime - r
+	}
+	return unixTime - r + interval
+}
@@ -0,0 +1,50 @@
+package candlestick
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	blocks := map[int64]*CandleSet{}
+	for _, cs := range walkBlocks(Interval1m, -2, -1, 1) {
+		blocks[cs.Meta.Block] = cs
+	}
+	blocks[1].Candles = blocks[1].Candles[:100]
+	blocks[1].Meta.Complete = false
+	var fetches int32
+	source := BlockSourceFunc(func(symbol AssetIdentifier, interval int64, block int64) (*CandleSet, error) {
+		atomic.AddInt32(&fetches, 1)
+		if cs, ok := blocks[block]; ok {
+			return cs, nil
+		}
+		return nil, ErrBlockNotFound
+	})
+
+	symbol := NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+	from := BlockToUnix(-2, Interval1m) + 4990*Interval1m + 30
+	to := BlockToUnix(1, Interval1m) + 200*Interval1m
+	candles, err := Range(source, symbol, Interval1m, from, to)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(candles) != 10+2*int(CandleSetSize)+200-1 || fetches != 4 {
+		fmt.Printf("unexpected number of candles %d or fetches %d\n", len(candles), fetches)
+		t.FailNow()
+	}
+	for i, c := range candles {
+		ts := BlockToUnix(-2, Interval1m) + int64(4991+i)*Interval1m
+		block := UnixToBlock(ts, Interval1m)
+		cs, ok := blocks[block]
+		expected := Candle{Time: ts, Missing: true}
+		if ok && cs.Index(ts) < int64(len(cs.Candles)) {
+			expected = *cs.AtTime(ts)
+		}
+		if c != expected || c.Time != ts {
+			fmt.Printf("candle %d at %d did not match\n", i, ts)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package candlestick
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// SeriesStorage selects how the values of an indicator series are encoded.
+// Decoding always yields float64 values; StorageFloat32 and StorageFixed
+// trade precision for size.
+type SeriesStorage uint8
+
+const (
+	StorageFloat64 = SeriesStorage(0)
+	// StorageFloat32 stores values as float32. Finite values beyond its
+	// range are rejected rather than stored as infinity.
+	StorageFloat32 = SeriesStorage(1)
+	// StorageFixed stores round(value * 10^Scale) as int32.
+	StorageFixed = SeriesStorage(2)
+)
+
+const maxFixedScale = 9
+
+func (s SeriesStorage) byteSize() int {
+	switch s {
+	case StorageFloat64:
+		return 8
+	case StorageFloat32, StorageFixed:
+		return 4
+	}
+	return 0
+}
+
+// Since version 4 a series stores its values as
+//
+//	storage uint8
+//	scale   uint8
+//	count   uint64
+//	missing bitmap, bit i%8 of byte i/8
+//	values  count * storage.byteSize()
+//
+// where missing values are written as zero.
+func appendSeriesValues(buf []byte, series *IndicatorSeries) ([]byte, error) {
+
+	storage := series.Storage
+	width := storage.byteSize()
+	if width == 0 || storage == StorageFixed && series.Scale > maxFixedScale {
+		return nil, ErrUnrepresentable
+	}
+	factor := math.Pow10(int(series.Scale))
+
+	// add storage and number of values
+	n := len(series.Values)
+	buf = append(buf, uint8(storage), series.Scale)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+
+	// add missing bitmap and values
+	o := len(buf)
+	buf = append(buf, make([]byte, (n+7)/8+n*width)...)
+	bitmap, values := buf[o:o+(n+7)/8], buf[o+(n+7)/8:]
+	for i, v := range series.Values {
+		if v.Missing {
+			bitmap[i/8] |= 1 << uint(i%8)
+			continue
+		}
+		switch storage {
+		case StorageFloat64:
+			binary.BigEndian.PutUint64(values[i*width:], math.Float64bits(v.Value))
+		case StorageFloat32:
+			f := float32(v.Value)
+			if math.IsInf(float64(f), 0) && !math.IsInf(v.Value, 0) {
+				return nil, ErrUnrepresentable
+			}
+			binary.BigEndian.PutUint32(values[i*width:], math.Float32bits(f))
+		case StorageFixed:
+			scaled := math.Round(v.Value * factor)
+			if math.IsNaN(scaled) || scaled < math.MinInt32 || scaled > math.MaxInt32 {
+				return nil, ErrUnrepresentable
+			}
+			binary.BigEndian.PutUint32(values[i*width:], uint32(int32(scaled)))
+		}
+	}
+
+	return buf, nil
+}
+
+func readSeriesValues(r *byteReader, series *IndicatorSeries) []IndicatorValue {
+
+	// decode storage and number of values
+	series.Storage = SeriesStorage(r.uint8())
+	series.Scale = r.uint8()
+	count := r.uint64()
+	width := series.Storage.byteSize()
+	if r.err != nil {
+		return nil
+	}
+	if width == 0 {
+		r.err = ErrCorruptData
+		return nil
+	}
+	if count > uint64(len(r.data)/width) {
+		r.err = ErrTruncated
+		return nil
+	}
+	n := int(count)
+	factor := math.Pow10(int(series.Scale))
+
+	// decode missing bitmap and values
+	bitmap := r.bytes((n + 7) / 8)
+	data := r.bytes(n * width)
+	if r.err != nil {
+		return nil
+	}
+	values := make([]IndicatorValue, n)
+	for i := range values {
+		if bitmap[i/8]&(1<<uint(i%8)) != 0 {
+			values[i].Missing = true
+			continue
+		}
+		switch series.Storage {
+		case StorageFloat64:
+			values[i].Value = math.Float64frombits(binary.BigEndian.Uint64(data[i*width:]))
+		case StorageFloat32:
+			values[i].Value = float64(math.Float32frombits(binary.BigEndian.Uint32(data[i*width:])))
+		case StorageFixed:
+			values[i].Value = float64(int32(binary.BigEndian.Uint32(data[i*width:]))) / factor
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"container/list"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/godoji/candlestick"
+)
+
+// ErrFetchPanicked is returned to callers that shared a fetch in which the
+// source panicked.
+var ErrFetchPanicked = errors.New("store: block source panicked")
+
+var candleByteSize = int64(unsafe.Sizeof(candlestick.Candle{}))
+
+// CacheOptions bound a Cache. Zero values disable the respective limit.
+type CacheOptions struct {
+	// MaxBlocks limits the number of cached blocks.
+	MaxBlocks int
+	// MaxBytes limits the estimated memory held by decoded candles.
+	MaxBytes int64
+	// CompleteTTL is how long complete blocks, which no longer change,
+	// are kept.
+	CompleteTTL time.Duration
+	// IncompleteTTL is how long incomplete blocks are kept before they
+	// are fetched again.
+	IncompleteTTL time.Duration
+}
+
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Shared    uint64 `json:"shared"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// Cache is a least recently used cache of decoded blocks in front of a
+// BlockSource. It is safe for concurrent use. Concurrent misses for the
+// same block share a single fetch. Returned blocks are shared between
+// callers and must not be modified.
+type Cache struct {
+	source  candlestick.BlockSource
+	options CacheOptions
+	now     func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	calls   map[string]*cacheCall
+	size    int64
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	key     string
+	cs      *candlestick.CandleSet
+	size    int64
+	expires time.Time
+}
+
+type cacheCall struct {
+	wg  sync.WaitGroup
+	cs  *candlestick.CandleSet
+	err error
+}
+
+func NewCache(source candlestick.BlockSource, options CacheOptions) *Cache {
+	return &Cache{
+		source:  source,
+		options: options,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		calls:   make(map[string]*cacheCall),
+	}
+}
+
+func cacheKey(symbol candlestick.AssetIdentifier, interval int64, block int64) string {
+	return symbol.ToString() + "/" + strconv.FormatInt(interval, 10) + "/" + strconv.FormatInt(block, 10)
+}
+
+func (c *Cache) GetCandleSet(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+	key := cacheKey(symbol, interval, block)
+
+	c.mu.Lock()
+
+	// serve from cache
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		if entry.expires.IsZero() || c.now().Before(entry.expires) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return entry.cs, nil
+		}
+		c.remove(el)
+	}
+
+	// join a fetch in flight
+	if call, ok := c.calls[key]; ok {
+		c.stats.Shared++
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.cs, call.err
+	}
+
+	// fetch from source
+	call := &cacheCall{}
+	call.wg.Add(1)
+	c.calls[key] = call
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// release the waiting callers even when the source panics, and only
+	// cache the block if it was not invalidated in the meantime
+	call.err = ErrFetchPanicked
+	defer func() {
+		c.mu.Lock()
+		if c.calls[key] == call {
+			delete(c.calls, key)
+			if call.err == nil {
+				c.add(key, call.cs)
+			}
+		}
+		c.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.cs, call.err = c.source.GetCandleSet(symbol, interval, block)
+
+	return call.cs, call.err
+}
+
+// Invalidate drops a block from the cache. A fetch of the block in flight
+// is not cached, and later requests fetch the block again.
+func (c *Cache) Invalidate(symbol candlestick.AssetIdentifier, interval int64, block int64) {
+	key := cacheKey(symbol, interval, block)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	delete(c.calls, key)
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Len returns the number of cached blocks.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *Cache) add(key string, cs *candlestick.CandleSet) {
+	entry := &cacheEntry{
+		key:  key,
+		cs:   cs,
+		size: int64(len(cs.Candles)) * candleByteSize,
+	}
+	ttl := c.options.CompleteTTL
+	if !cs.IsComplete() {
+		ttl = c.options.IncompleteTTL
+	}
+	if ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += entry.size
+
+	// evict least recently used blocks
+	for c.lru.Len() > 1 && (c.options.MaxBlocks > 0 && c.lru.Len() > c.options.MaxBlocks ||
+		c.options.MaxBytes > 0 && c.size > c.options.MaxBytes) {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	entry := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godoji/candlestick"
+)
+
+func TestCache(t *testing.T) {
+	var fetches int32
+	source := candlestick.BlockSourceFunc(func(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+		atomic.AddInt32(&fetches, 1)
+		return randomCandleSet(block, block != 9), nil
+	})
+	now := time.Unix(1685903959, 0)
+	cache := NewCache(source, CacheOptions{MaxBlocks: 3, IncompleteTTL: time.Minute})
+	cache.now = func() time.Time { return now }
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+
+	for _, block := range []int64{1, 2, 1, 1, 9, 9, 3, 1} {
+		if _, err := cache.GetCandleSet(symbol, candlestick.Interval1m, block); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Hits != 4 || stats.Misses != 4 || stats.Evictions != 1 || cache.Len() != 3 || fetches != 4 {
+		fmt.Printf("unexpected stats: %+v\n", stats)
+		t.FailNow()
+	}
+
+	// incomplete blocks expire
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.GetCandleSet(symbol, candlestick.Interval1m, 9); err != nil {
+		log.Fatalln(err)
+	}
+	if _, err := cache.GetCandleSet(symbol, candlestick.Interval1m, 1); err != nil {
+		log.Fatalln(err)
+	}
+	if fetches != 5 {
+		fmt.Printf("expected incomplete block to be fetched again, got %d fetches\n", fetches)
+		t.FailNow()
+	}
+}
+
+func TestCacheSingleFlight(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	source := candlestick.BlockSourceFunc(func(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return randomCandleSet(block, true), nil
+	})
+	cache := NewCache(source, CacheOptions{})
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+
+	var wg sync.WaitGroup
+	results := make([]*candlestick.CandleSet, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cs, err := cache.GetCandleSet(symbol, candlestick.Interval1m, 4)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			results[i] = cs
+		}(i)
+	}
+	for {
+		stats := cache.Stats()
+		if stats.Misses+stats.Shared == 10 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if fetches != 1 {
+		fmt.Printf("expected a single fetch, got %d\n", fetches)
+		t.FailNow()
+	}
+	for _, cs := range results {
+		if cs != results[0] {
+			fmt.Printf("concurrent callers got different blocks\n")
+			t.FailNow()
+		}
+	}
+}
+
+func TestCachePanic(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	source := candlestick.BlockSourceFunc(func(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			<-release
+			panic("source failed")
+		}
+		return randomCandleSet(block, true), nil
+	})
+	cache := NewCache(source, CacheOptions{})
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		_, _ = cache.GetCandleSet(symbol, candlestick.Interval1m, 4)
+	}()
+	for cache.Stats().Misses != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	shared := make(chan error)
+	go func() {
+		_, err := cache.GetCandleSet(symbol, candlestick.Interval1m, 4)
+		shared <- err
+	}()
+	for cache.Stats().Shared != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if <-panicked == nil || <-shared != ErrFetchPanicked {
+		fmt.Printf("expected the panic to reach the fetching caller and an error the sharing one\n")
+		t.FailNow()
+	}
+
+	// the block is fetched again
+	cs, err := cache.GetCandleSet(symbol, candlestick.Interval1m, 4)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if cs == nil || fetches != 2 {
+		fmt.Printf("expected the block to be fetched again, got %d fetches\n", fetches)
+		t.FailNow()
+	}
+}
+
+func TestCacheInvalidateInFlight(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	source := candlestick.BlockSourceFunc(func(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			<-release
+		}
+		return randomCandleSet(block, true), nil
+	})
+	cache := NewCache(source, CacheOptions{})
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+
+	stale := make(chan *candlestick.CandleSet)
+	go func() {
+		cs, err := cache.GetCandleSet(symbol, candlestick.Interval1m, 4)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		stale <- cs
+	}()
+	for cache.Stats().Misses != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cache.Invalidate(symbol, candlestick.Interval1m, 4)
+	close(release)
+	old := <-stale
+
+	cs, err := cache.GetCandleSet(symbol, candlestick.Interval1m, 4)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if cs == old || fetches != 2 {
+		fmt.Printf("expected the invalidated fetch not to be cached, got %d fetches\n", fetches)
+		t.FailNow()
+	}
+}
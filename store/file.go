@@ -0,0 +1,280 @@
+package store
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godoji/candlestick"
+)
+
+var ErrInvalidKey = errors.New("store: invalid key")
+var ErrBlockComplete = errors.New("store: block is already complete")
+
+const (
+	blockExtension   = ".bin"
+	partialExtension = ".partial.bin"
+)
+
+// FileStore keeps encoded candle and indicator blocks in a directory tree:
+//
+//	<root>/<broker>/<exchange>/<symbol>/<interval>/candles/<block>.bin
+//	<root>/<broker>/<exchange>/<symbol>/<interval>/indicators/<uid>/<block>.bin
+//
+// Incomplete blocks are stored as <block>.partial.bin and may be rewritten
+// until a complete version of the block is put, which replaces them. Once
+// complete, a block can no longer be replaced by an incomplete one. Files
+// are written to a temporary file first and renamed into place, so readers
+// never see partially written blocks.
+type FileStore struct {
+	root string
+	// Codec used for encoding candle blocks.
+	Codec candlestick.Codec
+}
+
+func NewFileStore(root string) *FileStore {
+	return &FileStore{root: root, Codec: candlestick.CodecRaw}
+}
+
+func (s *FileStore) GetCandleSet(symbol candlestick.AssetIdentifier, interval int64, block int64) (*candlestick.CandleSet, error) {
+	dir, err := s.candleDir(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readBlock(dir, block)
+	if err != nil {
+		return nil, err
+	}
+	return candlestick.DecodeCandleSet(data)
+}
+
+// PutCandleSet stores cs under the interval and block number of its meta
+// data.
+func (s *FileStore) PutCandleSet(symbol candlestick.AssetIdentifier, cs *candlestick.CandleSet) error {
+	dir, err := s.candleDir(symbol, cs.Meta.Interval)
+	if err != nil {
+		return err
+	}
+	data, err := candlestick.EncodeCandleSetWithCodec(cs, s.Codec)
+	if err != nil {
+		return err
+	}
+	return writeBlock(dir, cs.Meta.Block, cs.Meta.Complete, data)
+}
+
+func (s *FileStore) DeleteCandleSet(symbol candlestick.AssetIdentifier, interval int64, block int64) error {
+	dir, err := s.candleDir(symbol, interval)
+	if err != nil {
+		return err
+	}
+	return deleteBlock(dir, block)
+}
+
+// ListCandleSets returns the stored block numbers in ascending order.
+func (s *FileStore) ListCandleSets(symbol candlestick.AssetIdentifier, interval int64) ([]int64, error) {
+	dir, err := s.candleDir(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	return listBlocks(dir)
+}
+
+func (s *FileStore) GetIndicator(symbol candlestick.AssetIdentifier, uid string, interval int64, block int64) (*candlestick.Indicator, error) {
+	dir, err := s.indicatorDir(symbol, uid, interval)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readBlock(dir, block)
+	if err != nil {
+		return nil, err
+	}
+	return candlestick.DecodeIndicatorSet(data)
+}
+
+// PutIndicator stores ind under the UID, interval and block number of its
+// meta data.
+func (s *FileStore) PutIndicator(symbol candlestick.AssetIdentifier, ind *candlestick.Indicator) error {
+	dir, err := s.indicatorDir(symbol, ind.Meta.UID, ind.Meta.Interval)
+	if err != nil {
+		return err
+	}
+	data, err := candlestick.EncodeIndicatorSet(ind)
+	if err != nil {
+		return err
+	}
+	return writeBlock(dir, ind.Meta.Block, ind.Meta.Complete, data)
+}
+
+func (s *FileStore) DeleteIndicator(symbol candlestick.AssetIdentifier, uid string, interval int64, block int64) error {
+	dir, err := s.indicatorDir(symbol, uid, interval)
+	if err != nil {
+		return err
+	}
+	return deleteBlock(dir, block)
+}
+
+// ListIndicators returns the stored block numbers in ascending order.
+func (s *FileStore) ListIndicators(symbol candlestick.AssetIdentifier, uid string, interval int64) ([]int64, error) {
+	dir, err := s.indicatorDir(symbol, uid, interval)
+	if err != nil {
+		return nil, err
+	}
+	return listBlocks(dir)
+}
+
+func (s *FileStore) symbolDir(symbol candlestick.AssetIdentifier, interval int64) (string, error) {
+	if symbol == nil {
+		return "", ErrInvalidKey
+	}
+	parts := []string{s.root}
+	for _, part := range []string{symbol.Broker, symbol.Exchange, symbol.Symbol} {
+		escaped, err := pathComponent(part)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, escaped)
+	}
+	parts = append(parts, strconv.FormatInt(interval, 10))
+	return filepath.Join(parts...), nil
+}
+
+func (s *FileStore) candleDir(symbol candlestick.AssetIdentifier, interval int64) (string, error) {
+	dir, err := s.symbolDir(symbol, interval)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "candles"), nil
+}
+
+func (s *FileStore) indicatorDir(symbol candlestick.AssetIdentifier, uid string, interval int64) (string, error) {
+	dir, err := s.symbolDir(symbol, interval)
+	if err != nil {
+		return "", err
+	}
+	escaped, err := pathComponent(uid)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "indicators", escaped), nil
+}
+
+// pathComponent escapes a key so it forms exactly one path element.
+func pathComponent(key string) (string, error) {
+	if key == "" || key == "." || key == ".." {
+		return "", ErrInvalidKey
+	}
+	return url.PathEscape(key), nil
+}
+
+func blockPath(dir string, block int64, complete bool) string {
+	ext := partialExtension
+	if complete {
+		ext = blockExtension
+	}
+	return filepath.Join(dir, strconv.FormatInt(block, 10)+ext)
+}
+
+func readBlock(dir string, block int64) ([]byte, error) {
+	data, err := os.ReadFile(blockPath(dir, block, true))
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = os.ReadFile(blockPath(dir, block, false))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, candlestick.ErrBlockNotFound
+	}
+	return data, err
+}
+
+func writeBlock(dir string, block int64, complete bool, data []byte) error {
+	if !complete {
+		if _, err := os.Stat(blockPath(dir, block, true)); err == nil {
+			return ErrBlockComplete
+		}
+	}
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(blockPath(dir, block, complete), data)
+	if err != nil {
+		return err
+	}
+	if complete {
+		err = os.Remove(blockPath(dir, block, false))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+func deleteBlock(dir string, block int64) error {
+	found := false
+	for _, complete := range []bool{true, false} {
+		err := os.Remove(blockPath(dir, block, complete))
+		if err == nil {
+			found = true
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if !found {
+		return candlestick.ErrBlockNotFound
+	}
+	return nil
+}
+
+func listBlocks(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int64]bool)
+	var blocks []int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, blockExtension) {
+			continue
+		}
+		name = strings.TrimSuffix(strings.TrimSuffix(name, partialExtension), blockExtension)
+		block, err := strconv.ParseInt(name, 10, 64)
+		if err != nil || seen[block] {
+			continue
+		}
+		seen[block] = true
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i] < blocks[j]
+	})
+	return blocks, nil
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"testing"
+
+	"github.com/godoji/candlestick"
+)
+
+func randomCandleSet(block int64, complete bool) *candlestick.CandleSet {
+	cs := &candlestick.CandleSet{
+		Candles: make([]candlestick.Candle, 100),
+		Meta: candlestick.DataSetMeta{
+			UID:      "test_uid_name",
+			Block:    block,
+			Complete: complete,
+			Symbol:   "AAPLUSD",
+			Interval: candlestick.Interval1m,
+		},
+	}
+	for i := range cs.Candles {
+		cs.Candles[i] = candlestick.Candle{
+			Open:  rand.Float64(),
+			Close: rand.Float64(),
+			Time:  cs.TimeStampAtIndex(int64(i)),
+		}
+	}
+	return cs
+}
+
+func TestFileStoreCandles(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange/1", "AAPL/USD")
+
+	if _, err := s.GetCandleSet(symbol, candlestick.Interval1m, 3); err != candlestick.ErrBlockNotFound {
+		fmt.Printf("expected block not found, got %v\n", err)
+		t.FailNow()
+	}
+
+	// incomplete blocks can be rewritten until complete
+	for _, cs := range []*candlestick.CandleSet{randomCandleSet(3, false), randomCandleSet(3, false), randomCandleSet(3, true), randomCandleSet(-1, true)} {
+		if err := s.PutCandleSet(symbol, cs); err != nil {
+			log.Fatalln(err)
+		}
+		stored, err := s.GetCandleSet(symbol, candlestick.Interval1m, cs.Meta.Block)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if stored.Meta != cs.Meta || stored.Candles[5] != cs.Candles[5] {
+			fmt.Printf("stored block did not match\n")
+			t.FailNow()
+		}
+	}
+	if err := s.PutCandleSet(symbol, randomCandleSet(3, false)); err != ErrBlockComplete {
+		fmt.Printf("expected block complete, got %v\n", err)
+		t.FailNow()
+	}
+
+	blocks, err := s.ListCandleSets(symbol, candlestick.Interval1m)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(blocks) != 2 || blocks[0] != -1 || blocks[1] != 3 {
+		fmt.Printf("unexpected blocks: %v\n", blocks)
+		t.FailNow()
+	}
+	if err = s.DeleteCandleSet(symbol, candlestick.Interval1m, 3); err != nil {
+		log.Fatalln(err)
+	}
+	if _, err = s.GetCandleSet(symbol, candlestick.Interval1m, 3); err != candlestick.ErrBlockNotFound {
+		fmt.Printf("expected block not found, got %v\n", err)
+		t.FailNow()
+	}
+
+	// candles can be read through a range query
+	candles, err := candlestick.Range(s, symbol, candlestick.Interval1m, 0, 10*candlestick.Interval1m)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(candles) != 10 || !candles[0].Missing {
+		fmt.Printf("unexpected range result\n")
+		t.FailNow()
+	}
+}
+
+func TestFileStoreIndicators(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	symbol := candlestick.NewAssetIdentifier("broker", "exchange", "AAPLUSD")
+	ind := &candlestick.Indicator{
+		Series: map[string]*candlestick.IndicatorSeries{
+			"value": {Values: []candlestick.IndicatorValue{{Value: 1}, {Missing: true}}, Kind: candlestick.LineChart, Axis: candlestick.PriceAxis},
+		},
+		Meta: candlestick.IndicatorMeta{UID: "rsi(14)", Block: 7, Complete: true, Interval: candlestick.Interval1h, Name: "rsi", Parameters: []int{14}},
+	}
+	if err := s.PutIndicator(symbol, ind); err != nil {
+		log.Fatalln(err)
+	}
+	stored, err := s.GetIndicator(symbol, "rsi(14)", candlestick.Interval1h, 7)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !stored.Equal(ind) {
+		fmt.Printf("stored indicator did not match\n")
+		t.FailNow()
+	}
+	if _, err = s.GetIndicator(symbol, "..", candlestick.Interval1h, 7); err != ErrInvalidKey {
+		fmt.Printf("expected invalid key, got %v\n", err)
+		t.FailNow()
+	}
+}
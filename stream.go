@@ -0,0 +1,297 @@
+package candlestick
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// maxStreamMetaByteSize bounds the trailing meta data a CandleSetDecoder
+// is willing to buffer.
+const maxStreamMetaByteSize = 1 << 20
+
+var ErrCandleCount = errors.New("candlestick: candle count does not match header")
+
+// CandleSetEncoder writes a raw encoded CandleSet to a stream, one candle
+// at a time. The output is identical to EncodeCandleSet. As the format
+// starts with the number of candles, it has to be known up front:
+//
+//	enc := NewCandleSetEncoder(w)
+//	err := enc.WriteHeader(n)
+//	err = enc.WriteCandle(&c) // n times
+//	err = enc.Close(meta)
+type CandleSetEncoder struct {
+	w         *bufio.Writer
+	crc       hash.Hash32
+	remaining int
+	started   bool
+	buf       [candleSetByteSize]byte
+}
+
+func NewCandleSetEncoder(w io.Writer) *CandleSetEncoder {
+	return &CandleSetEncoder{
+		w:   bufio.NewWriter(w),
+		crc: crc32.New(castagnoli),
+	}
+}
+
+func (e *CandleSetEncoder) write(p []byte) error {
+	_, _ = e.crc.Write(p)
+	_, err := e.w.Write(p)
+	return err
+}
+
+func (e *CandleSetEncoder) WriteHeader(numberOfCandles int) error {
+	if e.started {
+		return ErrCandleCount
+	}
+	e.started = true
+	e.remaining = numberOfCandles
+
+	putBlockHeader(e.buf[:], candleSetMagic, blockHeader{
+		version: formatVersion1,
+		codec:   CodecRaw,
+		flags:   flagChecksum,
+		fields:  uint16(CandleFieldsAll),
+	})
+	binary.BigEndian.PutUint64(e.buf[headerByteSize:], uint64(numberOfCandles))
+	return e.write(e.buf[:headerByteSize+8])
+}
+
+func (e *CandleSetEncoder) WriteCandle(c *Candle) error {
+	if !e.started || e.remaining == 0 {
+		return ErrCandleCount
+	}
+	e.remaining--
+	putCandle(e.buf[:candleSetByteSize], c, CandleFieldsAll)
+	return e.write(e.buf[:candleSetByteSize])
+}
+
+// Close writes the meta data and checksum and flushes the stream. It does
+// not close the underlying writer.
+func (e *CandleSetEncoder) Close(meta DataSetMeta) error {
+	if !e.started || e.remaining != 0 {
+		return ErrCandleCount
+	}
+
+	// add meta data
+	var metaBuf bytes.Buffer
+	err := gob.NewEncoder(&metaBuf).Encode(meta)
+	if err != nil {
+		return err
+	}
+	err = e.write(metaBuf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// add checksum
+	_, err = e.w.Write(e.crc.Sum(nil))
+	if err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// Encode writes a whole CandleSet.
+func (e *CandleSetEncoder) Encode(b *CandleSet) error {
+	err := e.WriteHeader(len(b.Candles))
+	if err != nil {
+		return err
+	}
+	for i := range b.Candles {
+		err = e.WriteCandle(&b.Candles[i])
+		if err != nil {
+			return err
+		}
+	}
+	return e.Close(b.Meta)
+}
+
+// CandleSetDecoder reads a raw encoded CandleSet from a stream, one candle
+// at a time, in constant memory. The reader must hold a single block.
+//
+//	dec := NewCandleSetDecoder(r)
+//	for {
+//		c, err := dec.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+//	meta := dec.Meta()
+type CandleSetDecoder struct {
+	r          *bufio.Reader
+	crc        hash.Hash32
+	checksum   bool
+	fields     CandleField
+	recordSize int
+	length     int
+	remaining  int
+	started    bool
+	meta       DataSetMeta
+	err        error
+	buf        [candleSetByteSize]byte
+}
+
+func NewCandleSetDecoder(r io.Reader) *CandleSetDecoder {
+	return &CandleSetDecoder{
+		r:   bufio.NewReader(r),
+		crc: crc32.New(castagnoli),
+	}
+}
+
+func (d *CandleSetDecoder) read(p []byte) error {
+	_, err := io.ReadFull(d.r, p)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	if err != nil {
+		return err
+	}
+	_, _ = d.crc.Write(p)
+	return nil
+}
+
+func (d *CandleSetDecoder) readHeader() error {
+	if d.started {
+		return d.err
+	}
+	d.started = true
+
+	// read header, falling back to the legacy layout
+	d.fields = CandleFieldsAll
+	magic, err := d.r.Peek(len(candleSetMagic))
+	if err == nil && [4]byte(magic) == candleSetMagic {
+		if err = d.read(d.buf[:headerByteSize]); err != nil {
+			d.err = err
+			return err
+		}
+		h, _ := readBlockHeader(d.buf[:headerByteSize], candleSetMagic)
+		switch {
+		case h.version != formatVersion1:
+			d.err = ErrUnsupportedVersion
+		case h.codec != CodecRaw:
+			d.err = ErrUnsupportedCodec
+		}
+		if d.err != nil {
+			return d.err
+		}
+		d.checksum = h.flags&flagChecksum != 0
+		d.fields = CandleField(h.fields)
+	}
+	if d.fields == 0 || d.fields&^CandleFieldsAll != 0 {
+		d.err = ErrCorruptHeader
+		return d.err
+	}
+	d.recordSize = d.fields.recordSize()
+
+	// read number of candles
+	if err = d.read(d.buf[:8]); err != nil {
+		d.err = err
+		return err
+	}
+	count := binary.BigEndian.Uint64(d.buf[:8])
+	if count > uint64(math.MaxInt) {
+		d.err = ErrCorruptHeader
+		return d.err
+	}
+	d.length = int(count)
+	d.remaining = d.length
+	return nil
+}
+
+// Len returns the number of candles declared by the stream.
+func (d *CandleSetDecoder) Len() (int, error) {
+	err := d.readHeader()
+	return d.length, err
+}
+
+// Next returns the next candle. After the last candle it reads the meta
+// data, verifies the checksum and returns io.EOF.
+func (d *CandleSetDecoder) Next() (Candle, error) {
+	if err := d.readHeader(); err != nil {
+		return Candle{}, err
+	}
+	if d.remaining == 0 {
+		if d.err == nil {
+			d.err = d.readTrailer()
+		}
+		return Candle{}, d.err
+	}
+	if err := d.read(d.buf[:d.recordSize]); err != nil {
+		d.err = err
+		return Candle{}, err
+	}
+	d.remaining--
+	return readCandle(d.buf[:d.recordSize], d.fields), nil
+}
+
+func (d *CandleSetDecoder) readTrailer() error {
+
+	// read remaining bytes
+	trailer, err := io.ReadAll(io.LimitReader(d.r, maxStreamMetaByteSize+checksumByteSize+1))
+	if err != nil {
+		return err
+	}
+	if len(trailer) > maxStreamMetaByteSize+checksumByteSize {
+		return ErrBadMeta
+	}
+
+	// verify checksum
+	metaBytes := trailer
+	if d.checksum {
+		if len(trailer) < checksumByteSize {
+			return ErrTruncated
+		}
+		metaBytes = trailer[:len(trailer)-checksumByteSize]
+		_, _ = d.crc.Write(metaBytes)
+		if !bytes.Equal(d.crc.Sum(nil), trailer[len(metaBytes):]) {
+			return ErrChecksumMismatch
+		}
+	}
+
+	err = decodeMeta(metaBytes, &d.meta)
+	if err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// Meta returns the meta data, which is only available once Next has
+// returned io.EOF.
+func (d *CandleSetDecoder) Meta() DataSetMeta {
+	return d.meta
+}
+
+// Decode reads the remainder of the stream into a CandleSet.
+func (d *CandleSetDecoder) Decode() (*CandleSet, error) {
+	n, err := d.Len()
+	if err != nil {
+		return nil, err
+	}
+	if n > int(CandleSetSize) {
+		n = int(CandleSetSize)
+	}
+	candles := make([]Candle, 0, n)
+	for {
+		c, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	return &CandleSet{
+		Candles: candles,
+		Meta:    d.meta,
+	}, nil
+}
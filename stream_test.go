@@ -0,0 +1,100 @@
+package candlestick
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestCandleSetStream(t *testing.T) {
+	data := walkCandleSet()
+	var buf bytes.Buffer
+	err := NewCandleSetEncoder(&buf).Encode(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	bin, err := EncodeCandleSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !bytes.Equal(buf.Bytes(), bin) {
+		fmt.Printf("stream encoding differs from EncodeCandleSet\n")
+		t.FailNow()
+	}
+
+	for _, encoded := range [][]byte{bin, encodeLegacyCandleSet(data)} {
+		dec := NewCandleSetDecoder(bytes.NewReader(encoded))
+		n, err := dec.Len()
+		if err != nil || n != len(data.Candles) {
+			fmt.Printf("unexpected length %d: %v\n", n, err)
+			t.FailNow()
+		}
+		for i := 0; ; i++ {
+			c, err := dec.Next()
+			if err == io.EOF {
+				if i != len(data.Candles) {
+					fmt.Printf("stream ended after %d candles\n", i)
+					t.FailNow()
+				}
+				break
+			}
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if c != data.Candles[i] {
+				fmt.Printf("stream candle %d did not match\n", i)
+				t.FailNow()
+			}
+		}
+		if dec.Meta() != data.Meta {
+			fmt.Printf("stream meta did not match\n")
+			t.FailNow()
+		}
+	}
+}
+
+func TestCandleSetStreamCorrupt(t *testing.T) {
+	bin, err := EncodeCandleSet(walkCandleSet())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if _, err = NewCandleSetDecoder(bytes.NewReader(bin[:len(bin)/2])).Decode(); err != ErrTruncated {
+		fmt.Printf("expected truncated, got %v\n", err)
+		t.FailNow()
+	}
+	bin[headerByteSize+100] ^= 0x10
+	if _, err = NewCandleSetDecoder(bytes.NewReader(bin)).Decode(); err != ErrChecksumMismatch {
+		fmt.Printf("expected checksum mismatch, got %v\n", err)
+		t.FailNow()
+	}
+	enc := NewCandleSetEncoder(io.Discard)
+	if err = enc.WriteHeader(2); err != nil {
+		log.Fatalln(err)
+	}
+	if err = enc.Close(DataSetMeta{}); err == nil {
+		fmt.Printf("closing with missing candles did not fail\n")
+		t.FailNow()
+	}
+}
+
+func BenchmarkCandleSetStreamDecode(b *testing.B) {
+	bin, err := EncodeCandleSet(randomCandleSet())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dec := NewCandleSetDecoder(bytes.NewReader(bin))
+		for {
+			_, err = dec.Next()
+			if err != nil {
+				break
+			}
+		}
+		if err != io.EOF {
+			log.Fatalln(err)
+		}
+	}
+}
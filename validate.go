@@ -0,0 +1,209 @@
+package candlestick
+
+import "math"
+
+type ViolationKind string
+
+const (
+	NotFinite                = ViolationKind("NOT_FINITE")
+	HighBelowLow             = ViolationKind("HIGH_BELOW_LOW")
+	OpenOutOfRange           = ViolationKind("OPEN_OUT_OF_RANGE")
+	CloseOutOfRange          = ViolationKind("CLOSE_OUT_OF_RANGE")
+	NegativeVolume           = ViolationKind("NEGATIVE_VOLUME")
+	TakerVolumeExceedsVolume = ViolationKind("TAKER_VOLUME_EXCEEDS_VOLUME")
+	NegativeNumberOfTrades   = ViolationKind("NEGATIVE_NUMBER_OF_TRADES")
+	MisalignedTime           = ViolationKind("MISALIGNED_TIME")
+	UnknownInterval          = ViolationKind("UNKNOWN_INTERVAL")
+	TooManyCandles           = ViolationKind("TOO_MANY_CANDLES")
+)
+
+// Violation describes a single failed check. Index is the position of the
+// offending candle in its block, or -1 for violations of the block itself
+// and of candles validated on their own.
+type Violation struct {
+	Kind  ViolationKind `json:"kind"`
+	Field string        `json:"field,omitempty"`
+	Index int64         `json:"index"`
+}
+
+// Validate checks the candle for inconsistent OHLCV values. Missing
+// candles are not checked.
+func (c *Candle) Validate() []Violation {
+	if c.Missing {
+		return nil
+	}
+	var violations []Violation
+	add := func(kind ViolationKind, field string) {
+		violations = append(violations, Violation{Kind: kind, Field: field, Index: -1})
+	}
+
+	// non-finite values make the remaining checks meaningless
+	finite := true
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"open", c.Open},
+		{"high", c.High},
+		{"low", c.Low},
+		{"close", c.Close},
+		{"volume", c.Volume},
+		{"takerVolume", c.TakerVolume},
+	} {
+		if math.IsNaN(f.value) || math.IsInf(f.value, 0) {
+			add(NotFinite, f.name)
+			finite = false
+		}
+	}
+	if !finite {
+		return violations
+	}
+
+	if c.High < c.Low {
+		add(HighBelowLow, "high")
+	} else {
+		if c.Open < c.Low || c.Open > c.High {
+			add(OpenOutOfRange, "open")
+		}
+		if c.Close < c.Low || c.Close > c.High {
+			add(CloseOutOfRange, "close")
+		}
+	}
+	if c.Volume < 0 {
+		add(NegativeVolume, "volume")
+	}
+	if c.TakerVolume < 0 {
+		add(NegativeVolume, "takerVolume")
+	} else if c.TakerVolume > c.Volume {
+		add(TakerVolumeExceedsVolume, "takerVolume")
+	}
+	if c.NumberOfTrades < 0 {
+		add(NegativeNumberOfTrades, "numberOfTrades")
+	}
+	return violations
+}
+
+// Validate checks the block meta data and every candle in the block,
+// including that each present candle's Time matches its position. Time
+// stamps are not checked when the interval itself is unknown.
+func (b *CandleSet) Validate() []Violation {
+	var violations []Violation
+	knownInterval := isKnownInterval(b.Meta.Interval)
+	if !knownInterval {
+		violations = append(violations, Violation{Kind: UnknownInterval, Field: "interval", Index: -1})
+	}
+	if int64(len(b.Candles)) > CandleSetSize {
+		violations = append(violations, Violation{Kind: TooManyCandles, Index: -1})
+	}
+	for i := range b.Candles {
+		c := &b.Candles[i]
+		for _, v := range c.Validate() {
+			v.Index = int64(i)
+			violations = append(violations, v)
+		}
+		if knownInterval && !c.Missing && c.Time != b.TimeStampAtIndex(int64(i)) {
+			violations = append(violations, Violation{Kind: MisalignedTime, Field: "time", Index: int64(i)})
+		}
+	}
+	return violations
+}
+
+func isKnownInterval(interval int64) bool {
+	for _, known := range IntervalList {
+		if interval == known {
+			return true
+		}
+	}
+	return false
+}
+
+type SanitizePolicy uint8
+
+const (
+	// SanitizeMarkMissing marks invalid candles as missing and keeps
+	// their values.
+	SanitizeMarkMissing SanitizePolicy = iota
+	// SanitizeClamp repairs invalid candles: High and Low are swapped when
+	// inverted, Open and Close are clamped into [Low, High] and volumes
+	// and trade counts into their valid range. Candles with non-finite
+	// values cannot be repaired and are marked missing.
+	SanitizeClamp
+	// SanitizeDrop removes invalid candles. Within a block candles are
+	// positional, so they are replaced by an empty missing candle instead.
+	SanitizeDrop
+)
+
+// SanitizeCandles applies policy to all invalid candles and returns the
+// resulting candles together with the violations found. The input slice is
+// not modified.
+func SanitizeCandles(candles []Candle, policy SanitizePolicy) ([]Candle, []Violation) {
+	result := make([]Candle, 0, len(candles))
+	var violations []Violation
+	for i := range candles {
+		c := candles[i]
+		found := c.Validate()
+		for _, v := range found {
+			v.Index = int64(i)
+			violations = append(violations, v)
+		}
+		if len(found) > 0 && policy == SanitizeDrop {
+			continue
+		}
+		if len(found) > 0 {
+			sanitizeCandle(&c, policy, found)
+		}
+		result = append(result, c)
+	}
+	return result, violations
+}
+
+// Sanitize applies policy to all invalid candles of the block in place and
+// returns the violations found, as reported by Validate. Candles with a
+// misaligned Time are invalid as well, and SanitizeClamp resets their Time
+// to their position. Block level violations are reported but not repaired.
+func (b *CandleSet) Sanitize(policy SanitizePolicy) []Violation {
+	violations := b.Validate()
+	knownInterval := isKnownInterval(b.Meta.Interval)
+	for i := range b.Candles {
+		c := &b.Candles[i]
+		found := c.Validate()
+		if knownInterval && !c.Missing && c.Time != b.TimeStampAtIndex(int64(i)) {
+			found = append(found, Violation{Kind: MisalignedTime, Field: "time", Index: int64(i)})
+			if policy == SanitizeClamp {
+				c.Time = b.TimeStampAtIndex(int64(i))
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		if policy == SanitizeDrop {
+			*c = Candle{Time: b.TimeStampAtIndex(int64(i)), Missing: true}
+			continue
+		}
+		sanitizeCandle(c, policy, found)
+	}
+	return violations
+}
+
+func sanitizeCandle(c *Candle, policy SanitizePolicy, violations []Violation) {
+	if policy == SanitizeMarkMissing {
+		c.Missing = true
+		return
+	}
+	for _, v := range violations {
+		if v.Kind == NotFinite {
+			c.Missing = true
+			return
+		}
+	}
+	if c.High < c.Low {
+		c.High, c.Low = c.Low, c.High
+	}
+	c.Open = math.Min(math.Max(c.Open, c.Low), c.High)
+	c.Close = math.Min(math.Max(c.Close, c.Low), c.High)
+	c.Volume = math.Max(c.Volume, 0)
+	c.TakerVolume = math.Min(math.Max(c.TakerVolume, 0), c.Volume)
+	if c.NumberOfTrades < 0 {
+		c.NumberOfTrades = 0
+	}
+}
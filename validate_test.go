@@ -0,0 +1,156 @@
+package candlestick
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCandleValidate(t *testing.T) {
+	valid := Candle{Open: 10, High: 12, Low: 9, Close: 11, Volume: 100, TakerVolume: 40, NumberOfTrades: 7}
+	if v := valid.Validate(); len(v) != 0 {
+		fmt.Printf("unexpected violations %v\n", v)
+		t.FailNow()
+	}
+
+	cases := []struct {
+		candle Candle
+		kinds  []ViolationKind
+	}{
+		{Candle{Open: 10, High: 8, Low: 9, Close: 9}, []ViolationKind{HighBelowLow}},
+		{Candle{Open: 13, High: 12, Low: 9, Close: 8}, []ViolationKind{OpenOutOfRange, CloseOutOfRange}},
+		{Candle{Open: 10, High: 12, Low: 9, Close: 11, Volume: -1, TakerVolume: -2}, []ViolationKind{NegativeVolume, NegativeVolume}},
+		{Candle{Open: 10, High: 12, Low: 9, Close: 11, Volume: 1, TakerVolume: 2}, []ViolationKind{TakerVolumeExceedsVolume}},
+		{Candle{Open: math.NaN(), High: math.Inf(1), Low: 9, Close: 11}, []ViolationKind{NotFinite, NotFinite}},
+		{Candle{Open: 10, High: 12, Low: 9, Close: 11, NumberOfTrades: -1}, []ViolationKind{NegativeNumberOfTrades}},
+		{Candle{High: -1, Volume: -1, Missing: true}, nil},
+	}
+	for i, tc := range cases {
+		violations := tc.candle.Validate()
+		if len(violations) != len(tc.kinds) {
+			fmt.Printf("case %d: unexpected violations %v\n", i, violations)
+			t.FailNow()
+		}
+		for j, v := range violations {
+			if v.Kind != tc.kinds[j] || v.Index != -1 {
+				fmt.Printf("case %d: unexpected violation %v\n", i, v)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestCandleSetValidate(t *testing.T) {
+	cs := walkBlocks(Interval1m, 3)[0]
+	if v := cs.Validate(); len(v) != 0 {
+		fmt.Printf("unexpected violations %v\n", v[0])
+		t.FailNow()
+	}
+
+	cs.Candles[5].High = cs.Candles[5].Low - 1
+	cs.Candles[7].Time++
+	cs.Candles[8].Volume = math.NaN()
+	cs.Meta.Interval = 61
+	violations := cs.Validate()
+	if len(violations) != 3 || violations[0].Kind != UnknownInterval || violations[0].Index != -1 {
+		fmt.Printf("unexpected violations %v\n", violations)
+		t.FailNow()
+	}
+	cs.Meta.Interval = Interval1m
+	violations = cs.Validate()
+	expected := []Violation{
+		{Kind: HighBelowLow, Field: "high", Index: 5},
+		{Kind: MisalignedTime, Field: "time", Index: 7},
+		{Kind: NotFinite, Field: "volume", Index: 8},
+	}
+	if len(violations) != len(expected) {
+		fmt.Printf("unexpected violations %v\n", violations)
+		t.FailNow()
+	}
+	for i := range expected {
+		if violations[i] != expected[i] {
+			fmt.Printf("expected %v, got %v\n", expected[i], violations[i])
+			t.FailNow()
+		}
+	}
+
+	cs.Candles = append(cs.Candles, Candle{Missing: true})
+	if v := cs.Validate(); len(v) != 4 || v[0].Kind != TooManyCandles {
+		fmt.Printf("expected too many candles, got %v\n", v)
+		t.FailNow()
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	candles := []Candle{
+		{Open: 10, High: 12, Low: 9, Close: 11, Volume: 5, TakerVolume: 2},
+		{Open: 13, High: 9, Low: 12, Close: 8, Volume: 5, TakerVolume: 6},
+		{Open: math.NaN(), High: 12, Low: 9, Close: 11},
+	}
+
+	result, violations := SanitizeCandles(candles, SanitizeClamp)
+	if len(result) != 3 || len(violations) != 3 {
+		fmt.Printf("unexpected clamp result %v, %v\n", result, violations)
+		t.FailNow()
+	}
+	repaired := Candle{Open: 12, High: 12, Low: 9, Close: 9, Volume: 5, TakerVolume: 5}
+	if result[1] != repaired || !result[2].Missing || result[0] != candles[0] {
+		fmt.Printf("unexpected clamped candles %v\n", result)
+		t.FailNow()
+	}
+	if len(result[1].Validate()) != 0 || candles[1].High != 9 {
+		fmt.Printf("clamped candle is still invalid or input was modified\n")
+		t.FailNow()
+	}
+
+	result, _ = SanitizeCandles(candles, SanitizeMarkMissing)
+	if len(result) != 3 || result[0].Missing || !result[1].Missing || result[1].Open != 13 {
+		fmt.Printf("unexpected mark missing result %v\n", result)
+		t.FailNow()
+	}
+
+	result, _ = SanitizeCandles(candles, SanitizeDrop)
+	if len(result) != 1 || result[0] != candles[0] {
+		fmt.Printf("unexpected drop result %v\n", result)
+		t.FailNow()
+	}
+
+	cs := walkBlocks(Interval1m, 3)[0]
+	cs.Candles[5].Volume = -1
+	violations = cs.Sanitize(SanitizeDrop)
+	if len(violations) != 2 || cs.Candles[5] != (Candle{Time: cs.TimeStampAtIndex(5), Missing: true}) || len(cs.Validate()) != 0 {
+		fmt.Printf("unexpected sanitized block %v\n", violations)
+		t.FailNow()
+	}
+
+	// misaligned candles
+	for _, policy := range []SanitizePolicy{SanitizeMarkMissing, SanitizeClamp, SanitizeDrop} {
+		cs = walkBlocks(Interval1m, 3)[0]
+		cs.Candles[2].Time = 999
+		candle := cs.Candles[2]
+		violations = cs.Sanitize(policy)
+		if len(violations) != 1 || violations[0].Kind != MisalignedTime || len(cs.Validate()) != 0 {
+			fmt.Printf("policy %d: unexpected sanitized block %v, %v\n", policy, violations, cs.Validate())
+			t.FailNow()
+		}
+		c := cs.Candles[2]
+		switch policy {
+		case SanitizeMarkMissing:
+			if !c.Missing || c.Close != candle.Close {
+				fmt.Printf("misaligned candle should be marked missing, got %v\n", c)
+				t.FailNow()
+			}
+		case SanitizeClamp:
+			candle.Time = cs.TimeStampAtIndex(2)
+			if c != candle {
+				fmt.Printf("misaligned candle should be realigned, got %v\n", c)
+				t.FailNow()
+			}
+		case SanitizeDrop:
+			if c != (Candle{Time: cs.TimeStampAtIndex(2), Missing: true}) {
+				fmt.Printf("misaligned candle should be dropped, got %v\n", c)
+				t.FailNow()
+			}
+		}
+	}
+}
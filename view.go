@@ -0,0 +1,93 @@
+package candlestick
+
+// CandleSetView reads candles straight from an encoded CandleSet without
+// decoding the whole block. The data is not copied, so it may point into
+// a memory mapped file, and must not be modified while the view is used.
+// Only raw encoded blocks can be viewed; the checksum is not verified,
+// use VerifyBlock for that.
+type CandleSetView struct {
+	records    []byte
+	fields     CandleField
+	recordSize int
+	length     int
+	meta       DataSetMeta
+}
+
+func NewCandleSetView(data []byte) (*CandleSetView, error) {
+
+	// locate payload
+	payload := data
+	fields := CandleFieldsAll
+	h, ok := readBlockHeader(data, candleSetMagic)
+	if ok {
+		if h.version != formatVersion1 {
+			return nil, ErrUnsupportedVersion
+		}
+		if h.codec != CodecRaw {
+			return nil, ErrUnsupportedCodec
+		}
+		var err error
+		payload, err = unverifiedBlockPayload(data, h)
+		if err != nil {
+			return nil, err
+		}
+		fields = CandleField(h.fields)
+	}
+	if fields == 0 || fields&^CandleFieldsAll != 0 {
+		return nil, ErrCorruptHeader
+	}
+
+	// check records
+	recordSize := fields.recordSize()
+	length, err := readCount(payload, recordSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode meta data once
+	v := &CandleSetView{
+		records:    payload[8 : 8+length*recordSize],
+		fields:     fields,
+		recordSize: recordSize,
+		length:     length,
+	}
+	err = decodeMeta(payload[8+length*recordSize:], &v.meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (v *CandleSetView) Len() int {
+	return v.length
+}
+
+func (v *CandleSetView) Meta() DataSetMeta {
+	return v.meta
+}
+
+func (v *CandleSetView) TimeStampAtIndex(i int64) int64 {
+	return v.UnixFirst() + i*v.meta.Interval
+}
+
+func (v *CandleSetView) AtTime(timeStamp int64) Candle {
+	return v.AtIndex(v.Index(timeStamp))
+}
+
+func (v *CandleSetView) AtIndex(index int64) Candle {
+	o := index * int64(v.recordSize)
+	return readCandle(v.records[o:o+int64(v.recordSize)], v.fields)
+}
+
+func (v *CandleSetView) Index(timeStamp int64) int64 {
+	return (timeStamp - v.UnixFirst()) / v.meta.Interval
+}
+
+func (v *CandleSetView) UnixFirst() int64 {
+	return v.meta.Block * v.meta.Interval * CandleSetSize
+}
+
+func (v *CandleSetView) UnixLast() int64 {
+	return (v.meta.Block+1)*v.meta.Interval*CandleSetSize - v.meta.Interval
+}
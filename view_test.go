@@ -0,0 +1,77 @@
+package candlestick
+
+import (
+	"fmt"
+	"log"
+	"testing"
+)
+
+func TestCandleSetView(t *testing.T) {
+	data := walkCandleSet()
+	encoded, err := EncodeCandleSet(data)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, bin := range [][]byte{encodeLegacyCandleSet(data), encoded} {
+		view, err := NewCandleSetView(bin)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if view.Len() != len(data.Candles) || view.Meta() != data.Meta || view.UnixFirst() != data.UnixFirst() {
+			fmt.Printf("view meta did not match\n")
+			t.FailNow()
+		}
+		for i := range data.Candles {
+			if view.AtIndex(int64(i)) != data.Candles[i] {
+				fmt.Printf("view candle %d did not match\n", i)
+				t.FailNow()
+			}
+		}
+		ts := data.TimeStampAtIndex(1234)
+		if view.AtTime(ts) != *data.AtTime(ts) {
+			fmt.Printf("view candle at %d did not match\n", ts)
+			t.FailNow()
+		}
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = view.AtIndex(4321)
+		})
+		if allocs != 0 {
+			fmt.Printf("view allocated %f times per access\n", allocs)
+			t.FailNow()
+		}
+	}
+}
+
+func TestCandleSetViewCodec(t *testing.T) {
+	bin, err := EncodeCandleSetWithCodec(walkCandleSet(), CodecGorilla)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if _, err = NewCandleSetView(bin); err != ErrUnsupportedCodec {
+		fmt.Printf("expected unsupported codec, got %v\n", err)
+		t.FailNow()
+	}
+	bin, err = EncodeCandleSet(walkCandleSet())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if _, err = NewCandleSetView(bin[:len(bin)/2]); err == nil {
+		fmt.Printf("truncated view did not fail\n")
+		t.FailNow()
+	}
+}
+
+func BenchmarkCandleSetViewAtIndex(b *testing.B) {
+	bin, err := EncodeCandleSet(randomCandleSet())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	view, err := NewCandleSetView(bin)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = view.AtIndex(int64(i) % CandleSetSize)
+	}
+}